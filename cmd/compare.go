@@ -0,0 +1,143 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/llamerada-jp/colonio-simulator-view/pkg/model2d"
+	"github.com/llamerada-jp/colonio-simulator-view/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var (
+	compareView     string
+	otherURI        string
+	otherDataBase   string
+	otherCollection string
+)
+
+var compareCmd = &cobra.Command{
+	Use:   "compare",
+	Short: "View two sources side by side synchronized by the offset from the earliest time",
+	Long: `View two sources side by side synchronized by the offset from the earliest time.
+Both sources are collections of mongoDB, the other one is specified by --other-collection
+and optionally --other-uri and --other-database. Files are not supported as sources.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if chartSeconds != 0 || follow {
+			fmt.Fprintf(os.Stderr, "compare:--charts and --follow are not supported, the panel is used for the difference of metrics")
+			exitCode = 1
+			return
+		}
+		if tail {
+			fmt.Fprintf(os.Stderr, "compare:--tail is not supported, sources are aligned by the offset from the earliest time")
+			exitCode = 1
+			return
+		}
+		theme, err := utils.LoadTheme(themeName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "theme:%v", err)
+			exitCode = 1
+			return
+		}
+		coloring, err := model2d.ParseColoringMode(coloringName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "coloring:%v", err)
+			exitCode = 1
+			return
+		}
+		if compareView != "plane" && compareView != "sphere" {
+			fmt.Fprintf(os.Stderr, "view should be one of plane, sphere: %s", compareView)
+			exitCode = 1
+			return
+		}
+
+		// the other source is the same as the source except for specified flags
+		uri := otherURI
+		if len(uri) == 0 {
+			uri = mongoURI
+		}
+		database := otherDataBase
+		if len(database) == 0 {
+			database = mongoDataBase
+		}
+
+		level := resolveDetailLevel(cmd, compareView == "plane")
+		gl := utils.NewGL(imageName, theme.Background)
+		models := make([]*model2d.Model2D, 0, 2)
+		labels := make([]string, 0, 2)
+		for idx, source := range [][]string{
+			{mongoURI, mongoDataBase, mongoCollection},
+			{uri, database, otherCollection},
+		} {
+			// events are written only for the first source
+			events := ""
+			if idx == 0 {
+				events = eventsName
+			}
+			model, closeModel, err := newSourceModel(makeCompareDrawer(theme, coloring, level), gl, theme,
+				source[0], source[1], source[2], events)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v", err)
+				exitCode = 1
+				return
+			}
+			defer closeModel()
+
+			if compareView == "plane" {
+				model.SetValidator(model2d.NewPlaneValidator(validate))
+			} else {
+				model.SetValidator(model2d.NewSphereValidator(validate))
+			}
+			models = append(models, model)
+			labels = append(labels, source[1]+"/"+source[2])
+		}
+
+		compare := model2d.NewCompare(gl, theme, models[0], models[1], labels[0], labels[1])
+		if err = compare.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "compare:%v", err)
+			exitCode = 1
+			exitCode = 1
+		}
+	},
+}
+
+// makeCompareDrawer makes the drawer of the view for each source
+func makeCompareDrawer(theme *utils.Theme, coloring model2d.ColoringMode, level uint) model2d.Drawer {
+	if compareView == "sphere" {
+		drawer := model2d.NewSphereDrawer(level, theme, coloring)
+		drawer.SetVoronoi(voronoi)
+		return drawer
+	}
+	drawer := model2d.NewPlaneDrawer(level, theme, coloring)
+	drawer.SetVoronoi(voronoi)
+	return drawer
+}
+
+func init() {
+	flags := compareCmd.Flags()
+	flags.StringVar(&compareView, "view", "plane", "View to draw sources (plane, sphere)")
+	flags.StringVar(&otherURI, "other-uri", "", "URI of mongoDB to get the other source data, same as --uri if empty")
+	flags.StringVar(&otherDataBase, "other-database", "", "database name of mongoDB to get the other source data, same as --database if empty")
+	flags.StringVar(&otherCollection, "other-collection", "", "collection name of mongoDB to get the other source data")
+	if err := compareCmd.MarkFlagRequired("other-collection"); err != nil {
+		log.Fatalln("failed to mark the flag required:", err)
+	}
+	rootCmd.AddCommand(compareCmd)
+}
@@ -0,0 +1,65 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Print the difference of the network between --diff-from and --diff-to without opening a window",
+	Run: func(cmd *cobra.Command, args []string) {
+		from, to, err := parseDiffMoments()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "diff:%v", err)
+			exitCode = 1
+			return
+		}
+		if from == nil {
+			fmt.Fprintf(os.Stderr, "diff:--diff-from and --diff-to are required")
+			exitCode = 1
+			return
+		}
+
+		model, closeModel, err := newModel(nil, nil, nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v", err)
+			exitCode = 1
+			return
+		}
+		defer closeModel()
+
+		diff, err := model.Diff(*from, *to)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "diff:%v", err)
+			exitCode = 1
+			exitCode = 1
+			return
+		}
+		if err = diff.WriteText(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "diff:%v", err)
+			exitCode = 1
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+}
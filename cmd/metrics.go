@@ -0,0 +1,84 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/llamerada-jp/colonio-simulator-view/pkg/model2d"
+	"github.com/spf13/cobra"
+)
+
+var (
+	metricsFormat string
+	metricsOutput string
+)
+
+var metricsCmd = &cobra.Command{
+	Use:   "metrics",
+	Short: "Export metrics of each second without opening a window",
+	Run: func(cmd *cobra.Command, args []string) {
+		// check options before creating the output not to truncate the existing file by mistakes
+		format, err := model2d.ParseMetricsFormat(metricsFormat)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "metrics:%v", err)
+			exitCode = 1
+			return
+		}
+
+		model, closeModel, err := newModel(nil, nil, nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v", err)
+			exitCode = 1
+			return
+		}
+		defer closeModel()
+
+		// make output
+		var out io.Writer = os.Stdout
+		if metricsOutput != "-" {
+			f, err := os.Create(metricsOutput)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "output:%v", err)
+				exitCode = 1
+				return
+			}
+			defer f.Close()
+			out = f
+		}
+		writer := model2d.NewMetricsWriter(out, format)
+
+		err = model.Replay(writer.Write)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "metrics:%v", err)
+			exitCode = 1
+			exitCode = 1
+		}
+		if err = writer.Flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "metrics:%v", err)
+			exitCode = 1
+		}
+	},
+}
+
+func init() {
+	flags := metricsCmd.Flags()
+	flags.StringVar(&metricsFormat, "format", "csv", "Output format (csv, jsonl)")
+	flags.StringVarP(&metricsOutput, "output", "o", "-", "Output file name, - means stdout")
+	rootCmd.AddCommand(metricsCmd)
+}
@@ -28,20 +28,37 @@ var planeCmd = &cobra.Command{
 	Use:   "plane",
 	Short: "View data for plane",
 	Run: func(cmd *cobra.Command, args []string) {
-		// make accessor
-		accessor, err := utils.NewAccessor(mongoURI, mongoDataBase, mongoCollection)
+		theme, err := utils.LoadTheme(themeName)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "accessor:%v", err)
+			fmt.Fprintf(os.Stderr, "theme:%v", err)
+			exitCode = 1
+			return
+		}
+		coloring, err := model2d.ParseColoringMode(coloringName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "coloring:%v", err)
+			exitCode = 1
+			return
 		}
-		defer accessor.Disconnect()
 
 		// make drawer
-		drawer := &model2d.Plane{}
+		drawer := model2d.NewPlaneDrawer(resolveDetailLevel(cmd, true), theme, coloring)
+		drawer.SetVoronoi(voronoi)
+
+		model, closeModel, err := newModel(drawer, utils.NewGL(imageName, theme.Background), theme)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v", err)
+			exitCode = 1
+			return
+		}
+		defer closeModel()
 
-		model := model2d.NewInstance(accessor, drawer, utils.NewGL(imageName), follow, tail)
+		model.SetValidator(model2d.NewPlaneValidator(validate))
 		err = model.Run()
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "plane:%v", err)
+			exitCode = 1
+			exitCode = 1
 		}
 	},
 }
@@ -16,20 +16,51 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"os"
+	"time"
 
+	"github.com/llamerada-jp/colonio-simulator-view/pkg/model2d"
+	"github.com/llamerada-jp/colonio-simulator-view/pkg/utils"
 	"github.com/spf13/cobra"
 )
 
 var (
-	detailLevel     uint
-	follow          bool
-	imageName       string
-	mongoURI        string
-	mongoDataBase   string
-	mongoCollection string
-	tail            bool
+	chartSeconds     uint
+	coloringName     string
+	connectivityName string
+	convergeHold     uint
+	detailLevel      uint
+	detailLeval      uint
+	diffFrom         string
+	diffTo           string
+	eventsName       string
+	eventsFormat     string
+	filterExpr       string
+	filterModeName   string
+	focusNid         string
+	follow           bool
+	hud              bool
+	imageName        string
+	livenessName     string
+	minGroupSize     uint
+	mongoURI         string
+	mongoDataBase    string
+	mongoCollection  string
+	tail             bool
+	strict           bool
+	themeName        string
+	timeoutSeconds   uint
+	trailSeconds     uint
+	untilConverged   bool
+	validate         bool
+	voronoi          bool
+
+	// exitCode is set to 1 by commands failing by any error to exit with it after cleaning up
+	exitCode int
 )
 
 var rootCmd = &cobra.Command{
@@ -38,13 +69,38 @@ var rootCmd = &cobra.Command{
 
 func init() {
 	flags := rootCmd.PersistentFlags()
-	flags.UintVarP(&detailLevel, "detail-leval", "l", 0, "Whether to draw detailed information")
+	flags.UintVar(&chartSeconds, "charts", 0, "Seconds of time-series charts drawn next to the view, 0 means no charts")
+	flags.StringVar(&coloringName, "coloring", "group", "Attribute represented by node color (group, auth, node-link, seed-link, degree, age, staleness, nid)")
+	flags.StringVar(&connectivityName, "connectivity", "weak", "Links connecting nodes into a group (weak: either direction, mutual: both directions)")
+	flags.UintVar(&convergeHold, "converge-hold", 10, "Seconds to hold the stable state to decide the network converged")
+	flags.UintVarP(&detailLevel, "detail-level", "l", 0, "Detail level of links (0: required 2D, 1: +one-way, 2: +all links, 3: +1D ring), plane draws all links without it")
+	flags.UintVar(&detailLeval, "detail-leval", 0, "Former detail level (0: required 2D, 1: all links)")
+	if err := flags.MarkDeprecated("detail-leval", "use --detail-level instead, all links are 2 of it"); err != nil {
+		log.Fatalln("failed to deprecate the flag:", err)
+	}
+	flags.StringVar(&diffFrom, "diff-from", "", "Moment to compare the network with --diff-to, an offset from the earliest time like 90s or a time like '2006-01-02 15:04:05'")
+	flags.StringVar(&diffTo, "diff-to", "", "Moment to compare the network with --diff-from")
+	flags.StringVar(&eventsName, "events", "", "File name to write detected events, - means stdout")
+	flags.StringVar(&eventsFormat, "events-format", "text", "Format of events (text, jsonl)")
+	flags.StringVar(&filterExpr, "filter", "", "Expression selecting nodes to draw like 'group==1 && degree>5', a word without operators matches the prefix of nid")
+	flags.StringVar(&filterModeName, "filter-mode", "dim", "How nodes not matching the filter are drawn (dim, hide)")
+	flags.StringVar(&focusNid, "focus", "", "nid of the node kept at the center of the view (plane, sphere)")
 	flags.BoolVarP(&follow, "follow", "f", false, "Specify if the logs should be streamed")
+	flags.BoolVar(&hud, "hud", true, "Show metrics of the network on the view")
 	flags.StringVarP(&imageName, "image-name", "i", "", "Image path and name pattern like hoge/foo@.png (@ will be replace by index like 001, 002...)")
+	flags.StringVar(&livenessName, "liveness", "vouched", "Policy to decide nodes are alive (strict: by timeout, vouched: +linked from fresh neighbors, leave: until leave logs)")
+	flags.UintVar(&minGroupSize, "min-group-size", 3, "Minimum count of members to be regarded as a group, nodes in smaller groups are drawn as isolated")
 	flags.StringVarP(&mongoURI, "uri", "u", "mongodb://localhost:27017", "URI of mongoDB to get source data")
 	flags.StringVarP(&mongoDataBase, "database", "d", "simulation", "database name of mongoDB to get source data")
 	flags.StringVarP(&mongoCollection, "collection", "c", "logs", "collection name of mongoDB to get source data")
+	flags.BoolVar(&strict, "strict", false, "Stop with non-zero exit code by a bad record instead of skipping it")
 	flags.BoolVarP(&tail, "tail", "t", false, "Output start with tail 10 seconds of the source data")
+	flags.UintVar(&timeoutSeconds, "timeout", 4, "Seconds to regard nodes without logs as stale")
+	flags.UintVar(&trailSeconds, "trails", 0, "Seconds of trails of nodes drawn with velocity arrows, 0 means no trails (plane, sphere)")
+	flags.BoolVar(&untilConverged, "until-converged", false, "Stop when the network converged and exit with non-zero code if it never converges")
+	flags.BoolVar(&voronoi, "voronoi", false, "Shade Voronoi cells of nodes (plane, sphere)")
+	flags.BoolVar(&validate, "validate", false, "Validate required 2D links with Delaunay triangulation of node positions")
+	flags.StringVar(&themeName, "theme", "light", "Theme name (light, dark, colorblind) or path of YAML/JSON theme file")
 }
 
 // Execute is entry point for all commands
@@ -53,4 +109,141 @@ func Execute() {
 		fmt.Println(err)
 		os.Exit(1)
 	}
+	os.Exit(exitCode)
+}
+
+// resolveDetailLevel gets the detail level of links by flags, levels of the former flag and the plane drawing
+// all links without the flag are mapped to keep the former drawing
+func resolveDetailLevel(cmd *cobra.Command, plane bool) uint {
+	flags := cmd.Flags()
+	switch {
+	case flags.Changed("detail-level"):
+		return detailLevel
+	case flags.Changed("detail-leval"):
+		if plane || detailLeval != 0 {
+			return model2d.DetailAllLinks
+		}
+		return model2d.DetailRequired2D
+	case plane:
+		return model2d.DetailAllLinks
+	}
+	return detailLevel
+}
+
+// newModel makes the model of the source specified by flags, close should be called after using the model
+func newModel(drawer model2d.Drawer, gl *utils.GL, theme *utils.Theme) (*model2d.Model2D, func(), error) {
+	return newSourceModel(drawer, gl, theme, mongoURI, mongoDataBase, mongoCollection, eventsName)
+}
+
+// newSourceModel makes the model of the source with options shared by commands,
+// events are written to the file if the name is not empty
+func newSourceModel(drawer model2d.Drawer, gl *utils.GL, theme *utils.Theme,
+	uri, database, collection, events string) (*model2d.Model2D, func(), error) {
+	liveness, err := model2d.ParseLiveness(livenessName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("liveness:%w", err)
+	}
+	connectivity, err := model2d.ParseConnectivity(connectivityName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("connectivity:%w", err)
+	}
+	filter, err := model2d.ParseFilter(filterExpr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("filter:%w", err)
+	}
+	filterMode, err := model2d.ParseFilterMode(filterModeName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("filter-mode:%w", err)
+	}
+	from, to, err := parseDiffMoments()
+	if err != nil {
+		return nil, nil, fmt.Errorf("diff:%w", err)
+	}
+
+	// make accessor
+	accessor, err := utils.NewAccessor(uri, database, collection)
+	if err != nil {
+		return nil, nil, fmt.Errorf("accessor:%w", err)
+	}
+
+	eventWriter, closeEvents, err := makeEventWriter(events)
+	if err != nil {
+		accessor.Disconnect()
+		return nil, nil, fmt.Errorf("events:%w", err)
+	}
+
+	model := model2d.NewInstance(accessor, drawer, gl, theme, follow, tail)
+	model.SetHUD(hud)
+	model.SetCharts(int(chartSeconds))
+	model.SetConvergence(int(convergeHold), untilConverged)
+	model.SetEventWriter(eventWriter)
+	model.SetLiveness(time.Duration(timeoutSeconds)*time.Second, liveness)
+	model.SetConnectivity(connectivity)
+	model.SetMinGroupSize(int(minGroupSize))
+	model.SetStrict(strict)
+	model.SetFilter(filter, filterMode)
+	model.SetFocus(focusNid)
+	model.SetTrails(int(trailSeconds))
+	if from != nil {
+		model.SetDiff(*from, *to)
+	}
+
+	return model, func() {
+		accessor.Disconnect()
+		closeEvents()
+	}, nil
+}
+
+// parseDiffMoments parses moments to compare, they are nil if no moments are specified
+func parseDiffMoments() (*model2d.Moment, *model2d.Moment, error) {
+	if len(diffFrom) == 0 && len(diffTo) == 0 {
+		return nil, nil, nil
+	}
+	if len(diffFrom) == 0 || len(diffTo) == 0 {
+		return nil, nil, errors.New("both of --diff-from and --diff-to should be specified")
+	}
+	from, err := model2d.ParseMoment(diffFrom)
+	if err != nil {
+		return nil, nil, err
+	}
+	to, err := model2d.ParseMoment(diffTo)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &from, &to, nil
+}
+
+// makeEventWriter makes the writer of events to the file, the writer is nil if no file is specified
+func makeEventWriter(name string) (model2d.EventWriter, func(), error) {
+	if len(name) == 0 {
+		return nil, func() {}, nil
+	}
+
+	// check the format before creating the file not to truncate the existing file by mistakes
+	format, err := model2d.ParseEventFormat(eventsFormat)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var out io.Writer = os.Stdout
+	closeFile := func() {}
+	if name != "-" {
+		f, err := os.Create(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		out = f
+		closeFile = func() {
+			f.Close()
+		}
+	}
+
+	writer := model2d.NewEventWriter(out, format)
+	return writer, func() {
+		if err := writer.Flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "events:%v", err)
+			exitCode = 1
+		}
+		closeFile()
+	}, nil
 }
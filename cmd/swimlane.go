@@ -0,0 +1,69 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/llamerada-jp/colonio-simulator-view/pkg/model2d"
+	"github.com/llamerada-jp/colonio-simulator-view/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+var swimlaneSeconds uint
+
+var swimlaneCmd = &cobra.Command{
+	Use:   "swimlane",
+	Short: "View history of seed link, node link and auth status of each node",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(focusNid) != 0 {
+			fmt.Fprintf(os.Stderr, "focus:--focus is supported only by plane and sphere")
+			exitCode = 1
+			return
+		}
+		theme, err := utils.LoadTheme(themeName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "theme:%v", err)
+			exitCode = 1
+			return
+		}
+
+		// make drawer
+		drawer := model2d.NewSwimlaneDrawer(theme, int(swimlaneSeconds))
+
+		model, closeModel, err := newModel(drawer, utils.NewGL(imageName, theme.Background), theme)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v", err)
+			exitCode = 1
+			return
+		}
+		defer closeModel()
+
+		err = model.Run()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "swimlane:%v", err)
+			exitCode = 1
+			exitCode = 1
+		}
+	},
+}
+
+func init() {
+	flags := swimlaneCmd.Flags()
+	flags.UintVar(&swimlaneSeconds, "seconds", 0, "Seconds of the time range, 0 means from the first record")
+	rootCmd.AddCommand(swimlaneCmd)
+}
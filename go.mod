@@ -7,4 +7,6 @@ require (
 	github.com/go-gl/glfw/v3.3/glfw v0.0.0-20210727001814-0db043d8d5be
 	github.com/spf13/cobra v1.2.1
 	go.mongodb.org/mongo-driver v1.7.2
+	golang.org/x/image v0.0.0-20190802002840-cff245a6509b
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,246 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"math"
+	"time"
+
+	"github.com/llamerada-jp/colonio-simulator-view/pkg/utils"
+)
+
+const (
+	messagePubsubPublish = "pubsub publish"
+	messagePubsubDeliver = "pubsub deliver"
+	messageMapGet        = "map get"
+	messageMapSet        = "map set"
+
+	// activityLifetime is the time to keep drawing activities fading out
+	activityLifetime = 3 * time.Second
+	// circleDivisions is the count of segments to draw a circle
+	circleDivisions = 48
+)
+
+// ParameterPubsubPublish is for decoding parameter of `pubsub publish` log
+type ParameterPubsubPublish struct {
+	Name string `bson:"name"`
+	// X, Y and R are the position and the radius to publish, they are radian for sphere
+	X float64 `bson:"x"`
+	Y float64 `bson:"y"`
+	R float64 `bson:"r"`
+}
+
+// ParameterPubsubDeliver is for decoding parameter of `pubsub deliver` log
+type ParameterPubsubDeliver struct {
+	Name string `bson:"name"`
+}
+
+// ParameterMap is for decoding parameter of `map get` and `map set` logs output by the owner of the key
+type ParameterMap struct {
+	Key string `bson:"key"`
+}
+
+type activityKind int
+
+const (
+	activityPublish activityKind = iota
+	activityDeliver
+	activityMapGet
+	activityMapSet
+)
+
+// activity is a transient operation of pub/sub or map at the node
+type activity struct {
+	kind activityKind
+	nid  string
+	// name of pub/sub or key of map
+	name    string
+	x       float64
+	y       float64
+	r       float64
+	created time.Time
+}
+
+// activityDrawer is implemented by drawers which can draw activities of pub/sub and map
+type activityDrawer interface {
+	drawActivities(gl *utils.GL, nodes map[string]*Node, activities []*activity, current *time.Time)
+}
+
+func init() {
+	RegisterHandler(messagePubsubPublish, MessageHandler{
+		NewParam: func() interface{} {
+			return &ParameterPubsubPublish{}
+		},
+		Apply: func(node *Node, param interface{}) {
+			p := param.(*ParameterPubsubPublish)
+			node.activities = append(node.activities, &activity{
+				kind: activityPublish,
+				name: p.Name,
+				x:    p.X,
+				y:    p.Y,
+				r:    p.R,
+			})
+		},
+	})
+
+	RegisterHandler(messagePubsubDeliver, MessageHandler{
+		NewParam: func() interface{} {
+			return &ParameterPubsubDeliver{}
+		},
+		Apply: func(node *Node, param interface{}) {
+			node.activities = append(node.activities, &activity{
+				kind: activityDeliver,
+				name: param.(*ParameterPubsubDeliver).Name,
+			})
+		},
+	})
+
+	for message, kind := range map[string]activityKind{messageMapGet: activityMapGet, messageMapSet: activityMapSet} {
+		kind := kind
+		RegisterHandler(message, MessageHandler{
+			NewParam: func() interface{} {
+				return &ParameterMap{}
+			},
+			Apply: func(node *Node, param interface{}) {
+				node.activities = append(node.activities, &activity{
+					kind: kind,
+					name: param.(*ParameterMap).Key,
+				})
+			},
+		})
+	}
+}
+
+// collectActivities moves activities kept in nodes to the model and drops old activities
+func (s *Model2D) collectActivities(current *time.Time) {
+	for nid, node := range s.nodes {
+		for _, a := range node.activities {
+			a.nid = nid
+			a.created = *current
+			s.activities = append(s.activities, a)
+			switch a.kind {
+			case activityPublish:
+				s.publishes++
+			case activityDeliver:
+				s.deliveries++
+			default:
+				s.mapOperations++
+			}
+		}
+		node.activities = nil
+	}
+
+	alive := s.activities[:0]
+	for _, a := range s.activities {
+		if current.Sub(a.created) < activityLifetime {
+			alive = append(alive, a)
+		}
+	}
+	s.activities = alive
+}
+
+func (s *Model2D) drawActivities(nodes map[string]*Node, current *time.Time) {
+	if drawer, ok := s.drawer.(activityDrawer); ok && len(s.activities) != 0 {
+		drawer.drawActivities(s.gl, nodes, s.activities, current)
+	}
+}
+
+// activityColor gets the color fading out by the age of the activity
+func (p *painter) activityColor(a *activity, current *time.Time) utils.Color {
+	var c utils.Color
+	switch a.kind {
+	case activityPublish:
+		c = p.theme.Publish
+	case activityDeliver:
+		c = p.theme.Deliver
+	default:
+		c = p.theme.MapOwner
+	}
+	rate := 1.0 - float32(current.Sub(a.created))/float32(activityLifetime)
+	return p.theme.Background.Mix(c, rate)
+}
+
+// activityMarkSize gets the size of the box drawn on the node, 0 means no box
+func activityMarkSize(kind activityKind) float64 {
+	switch kind {
+	case activityDeliver:
+		return 14.0
+	case activityMapGet:
+		return 16.0
+	case activityMapSet:
+		return 20.0
+	}
+	return 0.0
+}
+
+func (s *Plane) drawActivities(gl *utils.GL, nodes map[string]*Node, activities []*activity, current *time.Time) {
+	for _, a := range activities {
+		gl.SetColor(s.activityColor(a, current))
+		if a.kind == activityPublish {
+			for i := 0; i < circleDivisions; i++ {
+				t1 := 2.0 * math.Pi * float64(i) / circleDivisions
+				t2 := 2.0 * math.Pi * float64(i+1) / circleDivisions
+				x1, y1 := s.convertCoordinate(a.x+a.r*math.Cos(t1), a.y+a.r*math.Sin(t1))
+				x2, y2 := s.convertCoordinate(a.x+a.r*math.Cos(t2), a.y+a.r*math.Sin(t2))
+				gl.Line3(x1, y1, -0.9, x2, y2, -0.9)
+			}
+			continue
+		}
+
+		if node, ok := nodes[a.nid]; ok && node.enable {
+			x, y := s.convertCoordinate(node.x, node.y)
+			gl.Box3(x, y, -1.0, activityMarkSize(a.kind))
+		}
+	}
+}
+
+func (s *Sphere) drawActivities(gl *utils.GL, nodes map[string]*Node, activities []*activity, current *time.Time) {
+	for _, a := range activities {
+		c := s.activityColor(a, current)
+		if a.kind == activityPublish {
+			// the circle of points at the angle r from the center on the sphere
+			center := sphericalPoint(a.x, a.y)
+			u := normalize(cross(center, point3{0.0, 1.0, 0.0}))
+			if norm(u) < 1e-9 {
+				u = point3{1.0, 0.0, 0.0}
+			}
+			v := cross(center, u)
+			circle := func(t float64) point3 {
+				return point3{
+					x: center.x*math.Cos(a.r) + (u.x*math.Cos(t)+v.x*math.Sin(t))*math.Sin(a.r),
+					y: center.y*math.Cos(a.r) + (u.y*math.Cos(t)+v.y*math.Sin(t))*math.Sin(a.r),
+					z: center.z*math.Cos(a.r) + (u.z*math.Cos(t)+v.z*math.Sin(t))*math.Sin(a.r),
+				}
+			}
+			for i := 0; i < circleDivisions; i++ {
+				p1 := circle(2.0 * math.Pi * float64(i) / circleDivisions)
+				p2 := circle(2.0 * math.Pi * float64(i+1) / circleDivisions)
+				p1 = s.rotate(p1)
+				p2 = s.rotate(p2)
+				gl.SetColor(s.reduceColorByZ(c, (p1.z+p2.z)/2.0))
+				gl.Line3(p1.x, p1.y, p1.z, p2.x, p2.y, p2.z)
+			}
+			continue
+		}
+
+		if node, ok := nodes[a.nid]; ok && node.enable {
+			x, y, z := s.convertCoordinate(node.x, node.y)
+			gl.SetColor(s.reduceColorByZ(c, z))
+			gl.Box3(x, y, z, activityMarkSize(a.kind))
+		}
+	}
+}
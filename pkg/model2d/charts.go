@@ -0,0 +1,132 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/llamerada-jp/colonio-simulator-view/pkg/utils"
+)
+
+const chartPanelWidth = 240
+
+type chart struct {
+	title  string
+	format string
+	value  func(*Metrics) float64
+	// fixedMax is the maximum value of the vertical axis, 0 means automatic
+	fixedMax float64
+}
+
+var charts = []chart{
+	{
+		title:  "nodes",
+		format: "%.0f",
+		value: func(m *Metrics) float64 {
+			return float64(m.Nodes)
+		},
+	},
+	{
+		title:  "groups",
+		format: "%.0f",
+		value: func(m *Metrics) float64 {
+			return float64(m.Groups)
+		},
+	},
+	{
+		title:  "one-way links",
+		format: "%.0f",
+		value: func(m *Metrics) float64 {
+			return float64(m.OneWayLinks)
+		},
+	},
+	{
+		title:  "largest group",
+		format: "%.2f",
+		value: func(m *Metrics) float64 {
+			if m.Nodes == 0 {
+				return 0.0
+			}
+			return float64(m.LargestGroup) / float64(m.Nodes)
+		},
+		fixedMax: 1.0,
+	},
+}
+
+// SetCharts sets seconds of time-series charts drawn next to the view, 0 means no charts
+func (s *Model2D) SetCharts(seconds int) {
+	s.chartSeconds = seconds
+}
+
+// recordHistory keeps metrics for the last seconds of charts
+func (s *Model2D) recordHistory() {
+	if s.chartSeconds <= 0 {
+		return
+	}
+	s.history = append(s.history, s.metrics)
+	if len(s.history) > s.chartSeconds {
+		s.history = s.history[len(s.history)-s.chartSeconds:]
+	}
+}
+
+// drawCharts draws sparklines on the panel at the right side of the window
+func (s *Model2D) drawCharts() {
+	if len(s.history) == 0 {
+		return
+	}
+	windowWidth, windowHeight := s.gl.WindowSize()
+	left := windowWidth - chartPanelWidth
+	chartHeight := windowHeight / len(charts)
+	last := s.history[len(s.history)-1]
+
+	s.gl.SetColor(s.theme.Panel)
+	s.gl.Rect(left, 0, chartPanelWidth, windowHeight)
+
+	for idx, c := range charts {
+		top := idx * chartHeight
+		s.gl.SetColor(s.theme.Text)
+		s.gl.Text(left+hudMargin, top+hudMargin, c.title+" "+fmt.Sprintf(c.format, c.value(last)))
+
+		// plot area
+		x1 := float64(left + hudMargin)
+		x2 := float64(windowWidth - hudMargin)
+		y1 := float64(top + hudMargin*2 + utils.FontHeight)
+		y2 := float64(top + chartHeight - hudMargin)
+		s.gl.SetColor(s.theme.Link)
+		s.gl.Polyline([]float64{x1, x2, x2, x1, x1}, []float64{y1, y1, y2, y2, y1})
+
+		max := c.fixedMax
+		if max == 0 {
+			for _, m := range s.history {
+				max = math.Max(max, c.value(m))
+			}
+			max = math.Max(max, 1.0)
+		}
+
+		xs := make([]float64, len(s.history))
+		ys := make([]float64, len(s.history))
+		for i, m := range s.history {
+			// the latest value is at the right end of the chart
+			elapsed := last.Time.Sub(m.Time).Seconds()
+			xs[i] = x2 - (x2-x1)*elapsed/float64(s.chartSeconds)
+			ys[i] = y2 - (y2-y1)*c.value(m)/max
+		}
+		s.gl.SetColor(s.theme.Chart)
+		s.gl.Polyline(xs, ys)
+	}
+}
@@ -0,0 +1,191 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/llamerada-jp/colonio-simulator-view/pkg/utils"
+)
+
+const comparePanelWidth = 240
+
+// compareMetrics are metrics compared on the panel, the title is up to 10 characters
+var compareMetrics = []chart{
+	{title: "nodes", format: "%6.0f", value: func(m *Metrics) float64 { return float64(m.Nodes) }},
+	{title: "groups", format: "%6.0f", value: func(m *Metrics) float64 { return float64(m.Groups) }},
+	{title: "largest", format: "%6.0f", value: func(m *Metrics) float64 { return float64(m.LargestGroup) }},
+	{title: "isolated", format: "%6.0f", value: func(m *Metrics) float64 { return float64(m.IsolatedNodes) }},
+	{title: "one-way", format: "%6.0f", value: func(m *Metrics) float64 { return float64(m.OneWayLinks) }},
+	{title: "degree", format: "%6.2f", value: func(m *Metrics) float64 { return m.AverageDegree }},
+	{title: "required%", format: "%6.1f", value: func(m *Metrics) float64 { return m.Established2DRate }},
+	{title: "seed", format: "%6.0f", value: func(m *Metrics) float64 { return float64(m.SeedConnected) }},
+	{title: "only-one", format: "%6.0f", value: func(m *Metrics) float64 { return float64(m.Onlyone) }},
+	{title: "packets", format: "%6.0f", value: func(m *Metrics) float64 { return float64(m.DeliveredPackets) }},
+	{title: "hops", format: "%6.0f", value: func(m *Metrics) float64 { return float64(m.PacketHops) }},
+}
+
+// Compare plays models side by side, they are synchronized by the offset from the earliest time of each source
+type Compare struct {
+	models []*Model2D
+	labels []string
+	gl     *utils.GL
+	theme  *utils.Theme
+}
+
+// NewCompare makes the comparison of two models made with the same gl, labels are shown on the panel
+func NewCompare(gl *utils.GL, theme *utils.Theme, left, right *Model2D, leftLabel, rightLabel string) *Compare {
+	return &Compare{
+		models: []*Model2D{left, right},
+		labels: []string{leftLabel, rightLabel},
+		gl:     gl,
+		theme:  theme,
+	}
+}
+
+// Run plays models until the end of the longer source, the shorter one keeps the last frame,
+// or until networks of all models converged if the convergence stops playback
+func (c *Compare) Run() error {
+	starts := make([]time.Time, len(c.models))
+	currents := make([]*time.Time, len(c.models))
+	lasts := make([]*time.Time, len(c.models))
+	var span time.Duration
+	for i, m := range c.models {
+		current, last, err := m.getTimeRange()
+		if err != nil {
+			return err
+		}
+		if err = m.updateByLogs(current); err != nil {
+			return err
+		}
+		m.keepSnapshots(current)
+		// the panel is used for the difference of metrics instead of charts
+		m.chartSeconds = 0
+		starts[i] = m.earliest
+		currents[i] = current
+		lasts[i] = last
+		if d := last.Sub(*current); d > span {
+			span = d
+		}
+	}
+
+	// setup opengl
+	c.gl.SetViews(len(c.models))
+	c.gl.SetPanelWidth(comparePanelWidth)
+	c.gl.Setup()
+	defer c.gl.Quit()
+	for i, m := range c.models {
+		m.view = i
+		c.gl.SelectView(i)
+		m.setupView()
+	}
+	c.gl.SetImageDigit(int(math.Log10(span.Seconds()) + 1.0))
+
+	var offset time.Duration
+	for c.gl.Loop() {
+		// the key to pause toggles all models together
+		c.gl.SetSaveFrame(!c.models[0].paused)
+		if !c.models[0].paused {
+			offset += time.Second
+			if offset > span {
+				break
+			}
+			for i, m := range c.models {
+				t := starts[i].Add(offset)
+				if t.After(*lasts[i]) {
+					continue
+				}
+				*currents[i] = t
+				if err := m.step(currents[i]); err != nil {
+					return err
+				}
+			}
+		}
+
+		for i, m := range c.models {
+			c.gl.SelectView(i)
+			if err := m.drawFrame(currents[i]); err != nil {
+				return err
+			}
+		}
+		c.gl.SelectView(0)
+		c.drawDiff(offset)
+
+		if c.shouldStop() {
+			break
+		}
+	}
+
+	return c.result()
+}
+
+// shouldStop returns true if playback should be stopped because networks of all models converged
+func (c *Compare) shouldStop() bool {
+	for _, m := range c.models {
+		if !m.convergence.shouldStop() {
+			return false
+		}
+	}
+	return true
+}
+
+// result gets the error of the first model which should converge but it did not
+func (c *Compare) result() error {
+	for i, m := range c.models {
+		if err := m.convergence.result(); err != nil {
+			return fmt.Errorf("%s: %w", c.labels[i], err)
+		}
+	}
+	return nil
+}
+
+// drawDiff draws metrics of both models and the difference of them on the panel at the right side of the window
+func (c *Compare) drawDiff(offset time.Duration) {
+	windowWidth, windowHeight := c.gl.WindowSize()
+	left := windowWidth - comparePanelWidth
+	columns := (comparePanelWidth - hudMargin*2) / utils.FontWidth
+
+	lines := []string{fmt.Sprintf("offset %s", offset)}
+	for i, label := range c.labels {
+		lines = append(lines, fmt.Sprintf("%c: %s", 'A'+i, label))
+	}
+	lines = append(lines, "", fmt.Sprintf("%-10s %6s %6s %6s", "", "A", "B", "B-A"))
+	a := c.models[0].metrics
+	b := c.models[1].metrics
+	if a != nil && b != nil {
+		for _, m := range compareMetrics {
+			va := m.value(a)
+			vb := m.value(b)
+			// show the sign of the difference
+			signed := strings.Replace(m.format, "%", "%+", 1)
+			lines = append(lines, fmt.Sprintf("%-10s "+m.format+" "+m.format+" "+signed, m.title, va, vb, vb-va))
+		}
+	}
+
+	c.gl.SetColor(c.theme.Panel)
+	c.gl.Rect(left, 0, comparePanelWidth, windowHeight)
+	c.gl.SetColor(c.theme.Text)
+	for idx, line := range lines {
+		if len(line) > columns {
+			line = line[:columns]
+		}
+		c.gl.Text(left+hudMargin, hudMargin+idx*utils.FontHeight, line)
+	}
+}
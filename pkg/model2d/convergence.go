@@ -0,0 +1,100 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ErrNotConverged is returned when the network did not converge before the end of the data
+var ErrNotConverged = errors.New("the network did not converge")
+
+// convergence detects the network is stable for the hold time
+type convergence struct {
+	hold        time.Duration
+	until       bool
+	start       time.Time
+	lastTime    time.Time
+	stableSince *time.Time
+	convergedAt *time.Time
+}
+
+// SetConvergence sets seconds to hold the stable state to decide the network converged,
+// and whether to stop playback when the network converged
+func (s *Model2D) SetConvergence(holdSeconds int, until bool) {
+	s.convergence.hold = time.Duration(holdSeconds) * time.Second
+	s.convergence.until = until
+}
+
+// isStable returns true if a single group contains all enabled nodes, no one-way links exist
+// and all required-2D links are established
+func isStable(m *Metrics) bool {
+	return m.Nodes > 0 &&
+		m.Groups == 1 &&
+		m.LargestGroup == m.Nodes &&
+		m.OneWayLinks == 0 &&
+		m.Established2D == m.Required2D
+}
+
+func (c *convergence) check(m *Metrics) {
+	if c.start.IsZero() {
+		c.start = m.Time
+	}
+	c.lastTime = m.Time
+	if c.convergedAt != nil {
+		return
+	}
+
+	if !isStable(m) {
+		c.stableSince = nil
+		return
+	}
+	if c.stableSince == nil {
+		t := m.Time
+		c.stableSince = &t
+	}
+	if m.Time.Sub(*c.stableSince) >= c.hold {
+		c.convergedAt = c.stableSince
+		log.Printf("converged at %s, %v after start", c.convergedAt.Format(hudTimeFormat), c.convergedAt.Sub(c.start))
+	}
+}
+
+// shouldStop returns true if playback should be stopped because the network converged
+func (c *convergence) shouldStop() bool {
+	return c.until && c.convergedAt != nil
+}
+
+// result gets the error if the network should converge but it did not
+func (c *convergence) result() error {
+	if c.until && c.convergedAt == nil {
+		return ErrNotConverged
+	}
+	return nil
+}
+
+func (c *convergence) String() string {
+	if c.convergedAt != nil {
+		return fmt.Sprintf("converged +%v", c.convergedAt.Sub(c.start))
+	}
+	if c.stableSince != nil {
+		return fmt.Sprintf("stable %v/%v", c.lastTime.Sub(*c.stableSince), c.hold)
+	}
+	return "not converged"
+}
@@ -0,0 +1,96 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConvergence(t *testing.T) {
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	stable := func(second int) *Metrics {
+		return &Metrics{
+			Time:          start.Add(time.Duration(second) * time.Second),
+			Nodes:         4,
+			Groups:        1,
+			LargestGroup:  4,
+			Required2D:    12,
+			Established2D: 12,
+		}
+	}
+	unstable := func(second int) *Metrics {
+		m := stable(second)
+		m.OneWayLinks = 1
+		return m
+	}
+
+	tests := []struct {
+		name      string
+		metrics   []*Metrics
+		converged bool
+		at        int
+	}{
+		{"stable for the hold", []*Metrics{unstable(0), stable(1), stable(2), stable(3), stable(4)}, true, 1},
+		{"shorter than the hold", []*Metrics{unstable(0), stable(1), stable(2), stable(3)}, false, 0},
+		{"broken while holding", []*Metrics{stable(0), stable(1), unstable(2), stable(3), stable(4), stable(5)}, false, 0},
+		{"kept after converged", []*Metrics{stable(0), stable(1), stable(2), stable(3), unstable(4)}, true, 0},
+	}
+	for _, tt := range tests {
+		c := convergence{hold: 3 * time.Second, until: true}
+		for _, m := range tt.metrics {
+			c.check(m)
+		}
+		if c.shouldStop() != tt.converged {
+			t.Errorf("%s: shouldStop() = %v", tt.name, c.shouldStop())
+		}
+		if tt.converged {
+			if c.result() != nil || !c.convergedAt.Equal(start.Add(time.Duration(tt.at)*time.Second)) {
+				t.Errorf("%s: converged at %v, result %v", tt.name, c.convergedAt, c.result())
+			}
+		} else if c.result() != ErrNotConverged {
+			t.Errorf("%s: result() = %v", tt.name, c.result())
+		}
+	}
+}
+
+func TestConvergenceWithStaleRequired2D(t *testing.T) {
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	s := &Model2D{
+		nodes: map[string]*Node{
+			"a": {enable: true, nid: "a", links: []string{"b", "c"}, required2D: []string{"b", "c", "x"}},
+			"b": {enable: true, nid: "b", links: []string{"a", "c"}, required2D: []string{"a", "c", "d"}},
+			"c": {enable: true, nid: "c", links: []string{"a", "b"}, required2D: []string{"a", "b"}},
+			// the node timed out and the unknown node x never established links
+			"d": {nid: "d"},
+		},
+		minGroupSize: defaultMinGroupSize,
+		convergence:  convergence{hold: 2 * time.Second, until: true},
+	}
+	for second := 0; second <= 2; second++ {
+		current := start.Add(time.Duration(second) * time.Second)
+		s.setGroupNumber()
+		m := s.computeMetrics(&current)
+		if m.Required2D != 6 || m.Established2D != 6 {
+			t.Fatalf("required %d established %d, want 6 and 6", m.Required2D, m.Established2D)
+		}
+		s.convergence.check(m)
+	}
+	if !s.convergence.shouldStop() {
+		t.Errorf("the network should converge: %s", s.convergence.String())
+	}
+}
@@ -0,0 +1,269 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"math"
+)
+
+const epsilon = 1e-12
+
+type point2 struct {
+	x float64
+	y float64
+}
+
+type point3 struct {
+	x float64
+	y float64
+	z float64
+}
+
+// triangle is a set of indexes of points, vertices are ordered counterclockwise
+type triangle [3]int
+
+type edge [2]int
+
+// delaunayPlane computes the Delaunay triangulation of points on a plane by Bowyer-Watson algorithm
+func delaunayPlane(points []point2) []triangle {
+	if len(points) < 3 {
+		return nil
+	}
+
+	// make a super triangle containing all points, it should be large enough not to lose thin triangles on the hull
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range points {
+		minX, maxX = math.Min(minX, p.x), math.Max(maxX, p.x)
+		minY, maxY = math.Min(minY, p.y), math.Max(maxY, p.y)
+	}
+	span := math.Max(math.Max(maxX-minX, maxY-minY), 1.0) * 1e6
+	midX, midY := (minX+maxX)/2.0, (minY+maxY)/2.0
+	n := len(points)
+	pts := append(append([]point2{}, points...),
+		point2{midX - span, midY - span},
+		point2{midX + span, midY - span},
+		point2{midX, midY + span})
+	triangles := []triangle{{n, n + 1, n + 2}}
+
+	for i := 0; i < n; i++ {
+		p := pts[i]
+		// remove triangles whose circumcircle contains the point and keep the boundary of the hole
+		edgeCount := make(map[edge]int)
+		kept := triangles[:0]
+		for _, t := range triangles {
+			if !inCircumcircle(pts[t[0]], pts[t[1]], pts[t[2]], p) {
+				kept = append(kept, t)
+				continue
+			}
+			for j := 0; j < 3; j++ {
+				edgeCount[normalizeEdge(t[j], t[(j+1)%3])]++
+			}
+		}
+		triangles = kept
+
+		for e, count := range edgeCount {
+			if count != 1 {
+				continue
+			}
+			t := triangle{e[0], e[1], i}
+			if orientation(pts[t[0]], pts[t[1]], pts[t[2]]) < 0 {
+				t[0], t[1] = t[1], t[0]
+			}
+			triangles = append(triangles, t)
+		}
+	}
+
+	// drop triangles sharing vertices with the super triangle
+	results := make([]triangle, 0, len(triangles))
+	for _, t := range triangles {
+		if t[0] < n && t[1] < n && t[2] < n {
+			results = append(results, t)
+		}
+	}
+	return results
+}
+
+// delaunaySphere computes the Delaunay triangulation of points on a unit sphere
+// as the convex hull of them
+func delaunaySphere(points []point3) []triangle {
+	n := len(points)
+	if n < 4 {
+		return nil
+	}
+
+	// find a tetrahedron with non-zero volume
+	i0 := 0
+	i1 := -1
+	for i := 1; i < n; i++ {
+		if norm(sub(points[i], points[i0])) > 1e-9 {
+			i1 = i
+			break
+		}
+	}
+	if i1 < 0 {
+		return nil
+	}
+	i2 := -1
+	for i := i1 + 1; i < n; i++ {
+		if norm(cross(sub(points[i1], points[i0]), sub(points[i], points[i0]))) > 1e-9 {
+			i2 = i
+			break
+		}
+	}
+	if i2 < 0 {
+		return nil
+	}
+	i3 := -1
+	normal := cross(sub(points[i1], points[i0]), sub(points[i2], points[i0]))
+	for i := i2 + 1; i < n; i++ {
+		if math.Abs(dot(normal, sub(points[i], points[i0]))) > 1e-9 {
+			i3 = i
+			break
+		}
+	}
+	if i3 < 0 {
+		return nil
+	}
+
+	// faces are ordered counterclockwise seen from outside
+	if dot(normal, sub(points[i3], points[i0])) > 0 {
+		i1, i2 = i2, i1
+	}
+	faces := []triangle{
+		{i0, i1, i2},
+		{i0, i3, i1},
+		{i1, i3, i2},
+		{i2, i3, i0},
+	}
+
+	for i := 0; i < n; i++ {
+		if i == i0 || i == i1 || i == i2 || i == i3 {
+			continue
+		}
+		p := points[i]
+
+		// remove faces visible from the point and keep the horizon
+		directed := make(map[edge]bool)
+		kept := faces[:0]
+		for _, f := range faces {
+			if !isVisible(points, f, p) {
+				kept = append(kept, f)
+				continue
+			}
+			for j := 0; j < 3; j++ {
+				directed[edge{f[j], f[(j+1)%3]}] = true
+			}
+		}
+		faces = kept
+
+		for e := range directed {
+			if !directed[edge{e[1], e[0]}] {
+				faces = append(faces, triangle{e[0], e[1], i})
+			}
+		}
+	}
+
+	return faces
+}
+
+// neighborsOf makes the list of adjacent vertices for each vertex of triangles
+func neighborsOf(triangles []triangle, count int) []map[int]bool {
+	neighbors := make([]map[int]bool, count)
+	for i := range neighbors {
+		neighbors[i] = make(map[int]bool)
+	}
+	for _, t := range triangles {
+		for j := 0; j < 3; j++ {
+			a, b := t[j], t[(j+1)%3]
+			neighbors[a][b] = true
+			neighbors[b][a] = true
+		}
+	}
+	return neighbors
+}
+
+func normalizeEdge(a, b int) edge {
+	if a > b {
+		return edge{b, a}
+	}
+	return edge{a, b}
+}
+
+// orientation is positive if a, b and c are counterclockwise
+func orientation(a, b, c point2) float64 {
+	return (b.x-a.x)*(c.y-a.y) - (b.y-a.y)*(c.x-a.x)
+}
+
+// inCircumcircle is true if d is inside of the circumcircle of counterclockwise triangle a, b, c,
+// the tolerance is relative to the magnitude of terms to keep small triangles of dense points
+func inCircumcircle(a, b, c, d point2) bool {
+	adx, ady := a.x-d.x, a.y-d.y
+	bdx, bdy := b.x-d.x, b.y-d.y
+	cdx, cdy := c.x-d.x, c.y-d.y
+	alift := adx*adx + ady*ady
+	blift := bdx*bdx + bdy*bdy
+	clift := cdx*cdx + cdy*cdy
+	det := alift*(bdx*cdy-cdx*bdy) -
+		blift*(adx*cdy-cdx*ady) +
+		clift*(adx*bdy-bdx*ady)
+	magnitude := alift*(math.Abs(bdx*cdy)+math.Abs(cdx*bdy)) +
+		blift*(math.Abs(adx*cdy)+math.Abs(cdx*ady)) +
+		clift*(math.Abs(adx*bdy)+math.Abs(bdx*ady))
+	return det > epsilon*magnitude
+}
+
+// isVisible is true if the point is beyond the plane of the face, the tolerance is relative to the size of the face
+// and the distance to keep points in a small area of the sphere where faces are almost flat
+func isVisible(points []point3, f triangle, p point3) bool {
+	normal := faceNormal(points, f)
+	d := sub(p, points[f[0]])
+	return dot(normal, d) > epsilon*norm(normal)*norm(d)
+}
+
+func faceNormal(points []point3, f triangle) point3 {
+	return cross(sub(points[f[1]], points[f[0]]), sub(points[f[2]], points[f[0]]))
+}
+
+func sub(a, b point3) point3 {
+	return point3{a.x - b.x, a.y - b.y, a.z - b.z}
+}
+
+func dot(a, b point3) float64 {
+	return a.x*b.x + a.y*b.y + a.z*b.z
+}
+
+func cross(a, b point3) point3 {
+	return point3{
+		a.y*b.z - a.z*b.y,
+		a.z*b.x - a.x*b.z,
+		a.x*b.y - a.y*b.x,
+	}
+}
+
+func norm(a point3) float64 {
+	return math.Sqrt(dot(a, a))
+}
+
+// sphericalPoint converts longitude and latitude to the point on the unit sphere
+func sphericalPoint(x, y float64) point3 {
+	return point3{
+		x: math.Cos(x) * math.Cos(y),
+		y: math.Sin(y),
+		z: math.Sin(x) * math.Cos(y),
+	}
+}
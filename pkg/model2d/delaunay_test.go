@@ -0,0 +1,137 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestDelaunayPlane(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	randomPoints := make([]point2, 50)
+	for i := range randomPoints {
+		randomPoints[i] = point2{random.Float64()*2.0 - 1.0, random.Float64()*2.0 - 1.0}
+	}
+	// dense points in a small area make tiny triangles
+	densePoints := make([]point2, 300)
+	for i := range densePoints {
+		densePoints[i] = point2{random.Float64() * 1e-3, random.Float64() * 1e-3}
+	}
+
+	tests := []struct {
+		name      string
+		points    []point2
+		triangles int
+	}{
+		{"triangle", []point2{{0, 0}, {1, 0}, {0, 1}}, 1},
+		// co-circular points of the square can be split by either diagonal
+		{"square", []point2{{0, 0}, {1, 0}, {1, 1}, {0, 1}}, 2},
+		{"square with center", []point2{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0.5, 0.5}}, 4},
+		{"grid", []point2{{0, 0}, {1, 0}, {2, 0}, {0, 1}, {1, 1}, {2, 1}, {0, 2}, {1, 2}, {2, 2}}, 8},
+		{"random", randomPoints, -1},
+		{"dense", densePoints, -1},
+	}
+	for _, tt := range tests {
+		triangles := delaunayPlane(tt.points)
+		if tt.triangles >= 0 && len(triangles) != tt.triangles {
+			t.Errorf("%s: %d triangles, want %d", tt.name, len(triangles), tt.triangles)
+		}
+		used := make(map[int]bool)
+		for _, tr := range triangles {
+			a, b, c := tt.points[tr[0]], tt.points[tr[1]], tt.points[tr[2]]
+			if orientation(a, b, c) <= 0 {
+				t.Errorf("%s: triangle %v is not counterclockwise", tt.name, tr)
+			}
+			// the circumcircle of each triangle should not contain other points
+			for i, p := range tt.points {
+				if i != tr[0] && i != tr[1] && i != tr[2] && inCircumcircle(a, b, c, p) {
+					t.Errorf("%s: circumcircle of %v contains %d", tt.name, tr, i)
+				}
+			}
+			for _, v := range tr {
+				used[v] = true
+			}
+		}
+		if len(used) != len(tt.points) {
+			t.Errorf("%s: %d of %d points are used", tt.name, len(used), len(tt.points))
+		}
+	}
+}
+
+func TestDelaunaySphere(t *testing.T) {
+	cube := make([]point3, 0, 8)
+	for _, x := range []float64{-1, 1} {
+		for _, y := range []float64{-1, 1} {
+			for _, z := range []float64{-1, 1} {
+				cube = append(cube, normalize(point3{x, y, z}))
+			}
+		}
+	}
+	// points on the same latitudes are co-circular with points on the next latitude
+	grid := make([]point3, 0)
+	for lat := -60; lat <= 60; lat += 30 {
+		for lon := 0; lon < 360; lon += 30 {
+			grid = append(grid, sphericalPoint(float64(lon)*math.Pi/180.0, float64(lat)*math.Pi/180.0))
+		}
+	}
+	random := rand.New(rand.NewSource(1))
+	dense := make([]point3, 2000)
+	for i := range dense {
+		dense[i] = sphericalPoint(random.Float64()*2.0*math.Pi, math.Asin(random.Float64()*2.0-1.0))
+	}
+	// faces in a small area are almost flat
+	cluster := []point3{{1, 0, 0}, {-1, 0, 0}, {0, 1, 0}, {0, -1, 0}, {0, 0, 1}, {0, 0, -1}}
+	for i := 0; i < 300; i++ {
+		cluster = append(cluster, sphericalPoint(random.Float64()*1e-4, random.Float64()*1e-4))
+	}
+
+	tests := []struct {
+		name   string
+		points []point3
+	}{
+		{"tetrahedron", []point3{
+			normalize(point3{1, 1, 1}), normalize(point3{1, -1, -1}),
+			normalize(point3{-1, 1, -1}), normalize(point3{-1, -1, 1}),
+		}},
+		{"octahedron", []point3{{1, 0, 0}, {-1, 0, 0}, {0, 1, 0}, {0, -1, 0}, {0, 0, 1}, {0, 0, -1}}},
+		{"cube", cube},
+		{"grid", grid},
+		{"dense", dense},
+		{"cluster", cluster},
+	}
+	for _, tt := range tests {
+		faces := delaunaySphere(tt.points)
+		// the convex hull of n points on the sphere has 2n-4 triangles
+		if want := 2*len(tt.points) - 4; len(faces) != want {
+			t.Errorf("%s: %d faces, want %d", tt.name, len(faces), want)
+		}
+		for _, f := range faces {
+			// faces are counterclockwise seen from outside and no points are beyond them
+			n := normalize(faceNormal(tt.points, f))
+			if dot(n, tt.points[f[0]]) <= 0 {
+				t.Errorf("%s: face %v faces inward", tt.name, f)
+			}
+			for i, p := range tt.points {
+				if d := dot(n, sub(p, tt.points[f[0]])); d > 1e-9 {
+					t.Errorf("%s: point %d is beyond the face %v by %g", tt.name, i, f, d)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,483 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/llamerada-jp/colonio-simulator-view/pkg/utils"
+)
+
+// Moment is the time to take the snapshot, written as the offset from the earliest time or the time
+type Moment struct {
+	offset time.Duration
+	time   time.Time
+}
+
+// ParseMoment parses the moment written like `90s` as the offset from the earliest time,
+// or like `2006-01-02 15:04:05` or `2006-01-02T15:04:05` as the time
+func ParseMoment(s string) (Moment, error) {
+	if offset, err := time.ParseDuration(s); err == nil {
+		return Moment{offset: offset}, nil
+	}
+	for _, layout := range []string{hudTimeFormat, "2006-01-02T15:04:05"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return Moment{time: t}, nil
+		}
+	}
+	return Moment{}, fmt.Errorf("moment should be an offset like 90s or a time like 2006-01-02 15:04:05: %s", s)
+}
+
+// at gets the time of the moment in the source having the earliest time
+func (m Moment) at(earliest time.Time) time.Time {
+	if m.time.IsZero() {
+		return earliest.Add(m.offset)
+	}
+	return m.time
+}
+
+// topologyState is the state of an enabled node in the snapshot,
+// slices are shared with the node because handlers replace them instead of modifying them
+type topologyState struct {
+	group      int
+	x          float64
+	y          float64
+	links      []string
+	required2D []string
+}
+
+// snapshot is the state of enabled nodes at the time
+type snapshot struct {
+	time  time.Time
+	nodes map[string]topologyState
+}
+
+func (s *Model2D) takeSnapshot(current *time.Time) *snapshot {
+	snap := &snapshot{
+		time:  *current,
+		nodes: make(map[string]topologyState),
+	}
+	for nid, node := range s.nodes {
+		if !node.enable {
+			continue
+		}
+		snap.nodes[nid] = topologyState{
+			group:      node.group,
+			x:          node.x,
+			y:          node.y,
+			links:      node.links,
+			required2D: node.required2D,
+		}
+	}
+	return snap
+}
+
+// pairs gets links between enabled nodes in either direction
+func (snap *snapshot) pairs() map[LinkPair]bool {
+	pairs := make(map[LinkPair]bool)
+	for nid, state := range snap.nodes {
+		for _, pairNid := range state.links {
+			if _, ok := snap.nodes[pairNid]; ok && nid != pairNid {
+				pairs[newLinkPair(nid, pairNid)] = true
+			}
+		}
+	}
+	return pairs
+}
+
+// LinkPair is a link between nodes regardless of the direction, A is less than B
+type LinkPair struct {
+	A string `json:"a"`
+	B string `json:"b"`
+}
+
+func newLinkPair(a, b string) LinkPair {
+	if b < a {
+		a, b = b, a
+	}
+	return LinkPair{a, b}
+}
+
+// GroupChange is the node which moved to another group
+type GroupChange struct {
+	Nid  string `json:"nid"`
+	From int    `json:"from"`
+	To   int    `json:"to"`
+}
+
+// Required2DChange is the node which changed required-2D peers
+type Required2DChange struct {
+	Nid     string   `json:"nid"`
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+// TopologyDiff is the difference of the network state between two times,
+// groups and required-2D are compared for nodes enabled at both times
+type TopologyDiff struct {
+	From              time.Time          `json:"from"`
+	To                time.Time          `json:"to"`
+	Appeared          []string           `json:"appeared"`
+	Disappeared       []string           `json:"disappeared"`
+	AddedLinks        []LinkPair         `json:"addedLinks"`
+	RemovedLinks      []LinkPair         `json:"removedLinks"`
+	GroupChanges      []GroupChange      `json:"groupChanges"`
+	Required2DChanges []Required2DChange `json:"required2DChanges"`
+	before            *snapshot
+	after             *snapshot
+}
+
+func diffSnapshots(before, after *snapshot) *TopologyDiff {
+	d := &TopologyDiff{
+		From:   before.time,
+		To:     after.time,
+		before: before,
+		after:  after,
+	}
+
+	for nid, state := range after.nodes {
+		prev, ok := before.nodes[nid]
+		if !ok {
+			d.Appeared = append(d.Appeared, nid)
+			continue
+		}
+		if prev.group != state.group {
+			d.GroupChanges = append(d.GroupChanges, GroupChange{nid, prev.group, state.group})
+		}
+		added := subtract(state.required2D, prev.required2D)
+		removed := subtract(prev.required2D, state.required2D)
+		if len(added) != 0 || len(removed) != 0 {
+			d.Required2DChanges = append(d.Required2DChanges, Required2DChange{nid, added, removed})
+		}
+	}
+	for nid := range before.nodes {
+		if _, ok := after.nodes[nid]; !ok {
+			d.Disappeared = append(d.Disappeared, nid)
+		}
+	}
+
+	beforePairs := before.pairs()
+	afterPairs := after.pairs()
+	for pair := range afterPairs {
+		if !beforePairs[pair] {
+			d.AddedLinks = append(d.AddedLinks, pair)
+		}
+	}
+	for pair := range beforePairs {
+		if !afterPairs[pair] {
+			d.RemovedLinks = append(d.RemovedLinks, pair)
+		}
+	}
+
+	sort.Strings(d.Appeared)
+	sort.Strings(d.Disappeared)
+	sortPairs(d.AddedLinks)
+	sortPairs(d.RemovedLinks)
+	sort.Slice(d.GroupChanges, func(i, j int) bool {
+		return d.GroupChanges[i].Nid < d.GroupChanges[j].Nid
+	})
+	sort.Slice(d.Required2DChanges, func(i, j int) bool {
+		return d.Required2DChanges[i].Nid < d.Required2DChanges[j].Nid
+	})
+	return d
+}
+
+// subtract gets sorted nids in a but not in b
+func subtract(a, b []string) []string {
+	result := make([]string, 0)
+	for _, nid := range a {
+		if !contains(b, nid) {
+			result = append(result, nid)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func sortPairs(pairs []LinkPair) {
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].A != pairs[j].A {
+			return pairs[i].A < pairs[j].A
+		}
+		return pairs[i].B < pairs[j].B
+	})
+}
+
+// Summary gets the line of counts of differences
+func (d *TopologyDiff) Summary() string {
+	return fmt.Sprintf("nodes +%d -%d links +%d -%d group %d required %d",
+		len(d.Appeared), len(d.Disappeared), len(d.AddedLinks), len(d.RemovedLinks),
+		len(d.GroupChanges), len(d.Required2DChanges))
+}
+
+// WriteText writes the summary and lists of differences
+func (d *TopologyDiff) WriteText(w io.Writer) error {
+	lines := []string{
+		fmt.Sprintf("%s -> %s", d.From.Format(hudTimeFormat), d.To.Format(hudTimeFormat)),
+		d.Summary(),
+	}
+	section := func(title string, count int) {
+		lines = append(lines, fmt.Sprintf("%s: %d", title, count))
+	}
+
+	section("appeared nodes", len(d.Appeared))
+	for _, nid := range d.Appeared {
+		lines = append(lines, "  + "+nid)
+	}
+	section("disappeared nodes", len(d.Disappeared))
+	for _, nid := range d.Disappeared {
+		lines = append(lines, "  - "+nid)
+	}
+	section("added links", len(d.AddedLinks))
+	for _, pair := range d.AddedLinks {
+		lines = append(lines, fmt.Sprintf("  + %s %s", pair.A, pair.B))
+	}
+	section("removed links", len(d.RemovedLinks))
+	for _, pair := range d.RemovedLinks {
+		lines = append(lines, fmt.Sprintf("  - %s %s", pair.A, pair.B))
+	}
+	section("group changes", len(d.GroupChanges))
+	for _, c := range d.GroupChanges {
+		lines = append(lines, fmt.Sprintf("  %s %d -> %d", c.Nid, c.From, c.To))
+	}
+	section("required 2D changes", len(d.Required2DChanges))
+	for _, c := range d.Required2DChanges {
+		line := "  " + c.Nid
+		if len(c.Added) != 0 {
+			line += " +" + strings.Join(c.Added, ",")
+		}
+		if len(c.Removed) != 0 {
+			line += " -" + strings.Join(c.Removed, ",")
+		}
+		lines = append(lines, line)
+	}
+
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// Diff replays the source without drawing and gets the difference of the network state between moments,
+// moments before the start are regarded as the start and moments after the last record as the last second
+func (s *Model2D) Diff(from, to Moment) (*TopologyDiff, error) {
+	current, last, err := s.getTimeRange()
+	if err != nil {
+		return nil, err
+	}
+	t1 := from.at(s.earliest)
+	t2 := to.at(s.earliest)
+	if t2.Before(t1) {
+		t1, t2 = t2, t1
+	}
+
+	if err = s.updateByLogs(current); err != nil {
+		return nil, err
+	}
+	after := s.firstSnapshot(current)
+	var before *snapshot
+	for {
+		if before == nil && !current.Before(t1) {
+			before = after
+		}
+		if !current.Before(t2) {
+			break
+		}
+		*current = current.Add(time.Second)
+		if current.After(*last) {
+			break
+		}
+		if err = s.step(current); err != nil {
+			return nil, err
+		}
+		after = s.takeSnapshot(current)
+	}
+
+	if before == nil {
+		before = after
+	}
+	return diffSnapshots(before, after), nil
+}
+
+// firstSnapshot takes the snapshot of the state by the records of the start time before the first step
+func (s *Model2D) firstSnapshot(current *time.Time) *snapshot {
+	s.setGroupNumber()
+	return s.takeSnapshot(current)
+}
+
+// keepSnapshots takes snapshots of moments set before playback at or before the start time,
+// it is skipped without moments to take nothing while viewing without the difference
+func (s *Model2D) keepSnapshots(current *time.Time) {
+	if !s.takesSnapshots(current) {
+		return
+	}
+	s.setGroupNumber()
+	s.keepSnapshot(current)
+}
+
+// takesSnapshots returns true if a moment is marked but its snapshot is not taken yet at the current time
+func (s *Model2D) takesSnapshots(current *time.Time) bool {
+	if s.diff != nil {
+		return false
+	}
+	for i, m := range s.diffMoments {
+		if s.diffSnapshots[i] == nil && !current.Before(m.at(s.earliest)) {
+			return true
+		}
+	}
+	return false
+}
+
+// keepSnapshot takes the snapshot of the current second for moments playback reached,
+// snapshots are kept only for marked moments and freed after getting the difference
+func (s *Model2D) keepSnapshot(current *time.Time) {
+	if !s.takesSnapshots(current) {
+		return
+	}
+	snap := s.takeSnapshot(current)
+	for i, m := range s.diffMoments {
+		if s.diffSnapshots[i] == nil && !current.Before(m.at(s.earliest)) {
+			s.diffSnapshots[i] = snap
+		}
+	}
+	s.resolveDiff()
+}
+
+// SetDiff sets moments to show the difference of the network state, it is shown when playback passes both
+func (s *Model2D) SetDiff(from, to Moment) {
+	s.clearDiff()
+	s.diffMoments = []Moment{from, to}
+	s.diffSnapshots = make([]*snapshot, 2)
+}
+
+// clearDiff clears marked moments, their snapshots and the difference
+func (s *Model2D) clearDiff() {
+	s.diffMoments = nil
+	s.diffSnapshots = nil
+	s.diff = nil
+}
+
+// resolveDiff gets the difference between snapshots of marked moments and frees them,
+// it waits for playback to pass both moments
+func (s *Model2D) resolveDiff() {
+	if len(s.diffMoments) != 2 || s.diff != nil {
+		return
+	}
+	before := s.diffSnapshots[0]
+	after := s.diffSnapshots[1]
+	if before == nil || after == nil {
+		return
+	}
+	if after.time.Before(before.time) {
+		before, after = after, before
+	}
+	s.diff = diffSnapshots(before, after)
+	s.diffSnapshots = nil
+	log.Printf("diff %s -> %s: %s", s.diff.From.Format(hudTimeFormat), s.diff.To.Format(hudTimeFormat), s.diff.Summary())
+}
+
+func (s *Model2D) setupDiffKeys() {
+	s.gl.OnKey('d', s.clearDiff)
+}
+
+// markTimeline marks the time by clicking on the timeline, the difference is shown for two marks,
+// a time already played is marked as the current time since past states are not kept
+func (s *Model2D) markTimeline(x, y int) bool {
+	if _, ok := s.drawer.(diffDrawer); !ok || !s.hud || s.metrics == nil {
+		return false
+	}
+	left, top, barWidth, span := s.timelineArea()
+	if span <= 0 || x < left || x >= left+barWidth || y < top || y >= top+timelineHeight {
+		return false
+	}
+	offset := time.Duration(float64(x-left) / float64(barWidth-1) * span * float64(time.Second)).Truncate(time.Second)
+	if len(s.diffMoments) == 2 {
+		s.clearDiff()
+	}
+	current := s.metrics.Time
+	t := s.start.Add(offset)
+	if t.Before(current) {
+		t = current
+	}
+	s.diffMoments = append(s.diffMoments, Moment{time: t})
+	s.diffSnapshots = append(s.diffSnapshots, nil)
+	log.Printf("mark: %s", t.Format(hudTimeFormat))
+	s.keepSnapshot(&current)
+	return true
+}
+
+// diffDrawer is implemented by drawers which can draw the difference of the network state
+type diffDrawer interface {
+	drawDiff(gl *utils.GL, diff *TopologyDiff)
+}
+
+func (s *Model2D) drawDiff() {
+	if drawer, ok := s.drawer.(diffDrawer); ok && s.diff != nil {
+		drawer.drawDiff(s.gl, s.diff)
+	}
+}
+
+// drawDiff draws nodes and links at the later time, removed ones are drawn at the earlier time
+func (s *Plane) drawDiff(gl *utils.GL, diff *TopologyDiff) {
+	s.forEachDiff(diff, func(c utils.Color, a, b topologyState) {
+		x1, y1 := s.convertCoordinate(a.x, a.y)
+		x2, y2 := s.convertCoordinate(b.x, b.y)
+		gl.SetColor(c)
+		gl.Line3(x1, y1, -0.95, x2, y2, -0.95)
+	}, func(c utils.Color, state topologyState, size float64) {
+		x, y := s.convertCoordinate(state.x, state.y)
+		gl.SetColor(c)
+		gl.Box3(x, y, -1.0, size)
+	})
+}
+
+// drawDiff draws nodes and links at the later time, removed ones are drawn at the earlier time
+func (s *Sphere) drawDiff(gl *utils.GL, diff *TopologyDiff) {
+	s.forEachDiff(diff, func(c utils.Color, a, b topologyState) {
+		s.arc(gl, sphericalPoint(a.x, a.y), sphericalPoint(b.x, b.y), c)
+	}, func(c utils.Color, state topologyState, size float64) {
+		x, y, z := s.convertCoordinate(state.x, state.y)
+		gl.SetColor(s.reduceColorByZ(c, z))
+		gl.Box3(x, y, z, size)
+	})
+}
+
+// forEachDiff calls functions to draw lines and boxes for each difference
+func (p *painter) forEachDiff(diff *TopologyDiff, line func(utils.Color, topologyState, topologyState),
+	box func(utils.Color, topologyState, float64)) {
+	for _, pair := range diff.AddedLinks {
+		line(p.theme.Added, diff.after.nodes[pair.A], diff.after.nodes[pair.B])
+	}
+	for _, pair := range diff.RemovedLinks {
+		line(p.theme.Removed, diff.before.nodes[pair.A], diff.before.nodes[pair.B])
+	}
+	for _, nid := range diff.Appeared {
+		box(p.theme.Added, diff.after.nodes[nid], 12.0)
+	}
+	for _, nid := range diff.Disappeared {
+		box(p.theme.Removed, diff.before.nodes[nid], 12.0)
+	}
+	for _, c := range diff.GroupChanges {
+		box(p.theme.Changed, diff.after.nodes[c.Nid], 16.0)
+	}
+	for _, c := range diff.Required2DChanges {
+		box(p.theme.Changed, diff.after.nodes[c.Nid], 20.0)
+	}
+}
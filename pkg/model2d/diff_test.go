@@ -0,0 +1,168 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	before := &snapshot{time: start, nodes: map[string]topologyState{
+		"a": {group: 1, links: []string{"b", "c"}, required2D: []string{"b", "c"}},
+		"b": {group: 1, links: []string{"a"}, required2D: []string{"a"}},
+		"c": {group: 1, links: []string{"a"}},
+		"e": {group: 2, links: []string{"x"}},
+	}}
+	after := &snapshot{time: start.Add(time.Minute), nodes: map[string]topologyState{
+		"a": {group: 2, links: []string{"d", "b"}, required2D: []string{"d", "b"}},
+		"b": {group: 1},
+		"d": {group: 2, links: []string{"a"}},
+		"e": {group: 2, links: []string{"x"}},
+	}}
+	d := diffSnapshots(before, after)
+
+	if !d.From.Equal(before.time) || !d.To.Equal(after.time) {
+		t.Errorf("time range = %v -> %v", d.From, d.To)
+	}
+	if !reflect.DeepEqual(d.Appeared, []string{"d"}) {
+		t.Errorf("Appeared = %v", d.Appeared)
+	}
+	if !reflect.DeepEqual(d.Disappeared, []string{"c"}) {
+		t.Errorf("Disappeared = %v", d.Disappeared)
+	}
+	// a link in either direction is regarded as the link, links to unknown nodes are ignored
+	if !reflect.DeepEqual(d.AddedLinks, []LinkPair{{"a", "d"}}) {
+		t.Errorf("AddedLinks = %v", d.AddedLinks)
+	}
+	if !reflect.DeepEqual(d.RemovedLinks, []LinkPair{{"a", "c"}}) {
+		t.Errorf("RemovedLinks = %v", d.RemovedLinks)
+	}
+	if !reflect.DeepEqual(d.GroupChanges, []GroupChange{{"a", 1, 2}}) {
+		t.Errorf("GroupChanges = %v", d.GroupChanges)
+	}
+	if !reflect.DeepEqual(d.Required2DChanges, []Required2DChange{
+		{"a", []string{"d"}, []string{"c"}},
+		{"b", []string{}, []string{"a"}},
+	}) {
+		t.Errorf("Required2DChanges = %v", d.Required2DChanges)
+	}
+
+	var buf bytes.Buffer
+	if err := d.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"2020-06-01 12:00:00 -> 2020-06-01 12:01:00",
+		"nodes +1 -1 links +1 -1 group 1 required 2",
+		"  + a d",
+		"  a 1 -> 2",
+		"  a +d -c",
+		"  b -a",
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("WriteText() does not contain %q:\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestDiffSnapshotsSame(t *testing.T) {
+	snap := &snapshot{nodes: map[string]topologyState{
+		"a": {group: 1, links: []string{"b"}, required2D: []string{"b"}},
+		"b": {group: 1, links: []string{"a"}, required2D: []string{"a"}},
+	}}
+	d := diffSnapshots(snap, snap)
+	if d.Summary() != "nodes +0 -0 links +0 -0 group 0 required 0" {
+		t.Errorf("Summary() = %s", d.Summary())
+	}
+}
+
+func TestParseMoment(t *testing.T) {
+	earliest := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		s    string
+		want time.Time
+	}{
+		{"0s", earliest},
+		{"90s", earliest.Add(90 * time.Second)},
+		{"2020-06-01 12:34:56", time.Date(2020, 6, 1, 12, 34, 56, 0, time.UTC)},
+		{"2020-06-01T12:34:56", time.Date(2020, 6, 1, 12, 34, 56, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		m, err := ParseMoment(tt.s)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.s, err)
+			continue
+		}
+		if got := m.at(earliest); !got.Equal(tt.want) {
+			t.Errorf("%s: at() = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+	for _, s := range []string{"", "90", "yesterday", "2020-06-01"} {
+		if _, err := ParseMoment(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestKeepSnapshot(t *testing.T) {
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	s := &Model2D{
+		nodes:    map[string]*Node{"a": {nid: "a", enable: true}},
+		earliest: start,
+	}
+
+	// nothing is kept without moments
+	for i := 0; i < 3; i++ {
+		current := start.Add(time.Duration(i) * time.Second)
+		s.keepSnapshot(&current)
+	}
+	if s.diffSnapshots != nil || s.diff != nil {
+		t.Fatalf("snapshots are kept without moments")
+	}
+
+	s.SetDiff(Moment{offset: 4 * time.Second}, Moment{offset: 2 * time.Second})
+	for i := 0; i <= 4; i++ {
+		current := start.Add(time.Duration(i) * time.Second)
+		if i == 3 {
+			s.nodes["b"] = &Node{nid: "b", enable: true, links: []string{"a"}}
+		}
+		s.keepSnapshot(&current)
+		if i < 4 && s.diff != nil {
+			t.Fatalf("difference is resolved at %d", i)
+		}
+		if i == 2 && (s.diffSnapshots[0] != nil || s.diffSnapshots[1] == nil) {
+			t.Fatalf("snapshots at 2 = %v", s.diffSnapshots)
+		}
+	}
+	if s.diff == nil {
+		t.Fatalf("difference is not resolved")
+	}
+	if s.diffSnapshots != nil {
+		t.Errorf("snapshots are not freed")
+	}
+	if !s.diff.From.Equal(start.Add(2*time.Second)) || !s.diff.To.Equal(start.Add(4*time.Second)) {
+		t.Errorf("time range = %v -> %v", s.diff.From, s.diff.To)
+	}
+	if !reflect.DeepEqual(s.diff.Appeared, []string{"b"}) || !reflect.DeepEqual(s.diff.AddedLinks, []LinkPair{{"a", "b"}}) {
+		t.Errorf("Appeared = %v, AddedLinks = %v", s.diff.Appeared, s.diff.AddedLinks)
+	}
+}
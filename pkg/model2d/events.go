@@ -0,0 +1,312 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EventKind is the kind of notable events detected during playback
+type EventKind string
+
+const (
+	EventJoin        EventKind = "join"
+	EventTimeout     EventKind = "timeout"
+	EventGroupSplit  EventKind = "group-split"
+	EventGroupMerge  EventKind = "group-merge"
+	EventOneWayLink  EventKind = "one-way-link"
+	EventSeedLost    EventKind = "seed-lost"
+	EventAuthFailure EventKind = "auth-failure"
+)
+
+// flashDuration is the time to flash nodes affected by events
+const flashDuration = 3 * time.Second
+
+// Event is a notable change of the network between two frames
+type Event struct {
+	Time time.Time `json:"time"`
+	Kind EventKind `json:"kind"`
+	// Nids are nodes affected by the event
+	Nids   []string `json:"nids"`
+	Detail string   `json:"detail,omitempty"`
+}
+
+func (e *Event) String() string {
+	text := fmt.Sprintf("%s %s %s", e.Time.Format(hudTimeFormat), e.Kind, strings.Join(e.Nids, ","))
+	if len(e.Detail) != 0 {
+		text += " (" + e.Detail + ")"
+	}
+	return text
+}
+
+// EventWriter writes events as a stream
+type EventWriter interface {
+	Write(*Event) error
+	Flush() error
+}
+
+type textEventWriter struct {
+	writer io.Writer
+}
+
+type jsonEventWriter struct {
+	encoder *json.Encoder
+}
+
+// EventFormat is the format to write events
+type EventFormat int
+
+const (
+	// EventText writes a line of text for each event
+	EventText EventFormat = iota
+	// EventJSONL writes a JSON object for each event (JSON Lines)
+	EventJSONL
+)
+
+var eventFormatNames = []string{
+	"text",
+	"jsonl",
+}
+
+// ParseEventFormat gets the format of events by the name
+func ParseEventFormat(name string) (EventFormat, error) {
+	for idx, v := range eventFormatNames {
+		if v == name {
+			return EventFormat(idx), nil
+		}
+	}
+	return EventText, fmt.Errorf("format should be one of %s: %s",
+		strings.Join(eventFormatNames, ", "), name)
+}
+
+func (f EventFormat) String() string {
+	return eventFormatNames[f]
+}
+
+// NewEventWriter makes a writer for the format
+func NewEventWriter(w io.Writer, format EventFormat) EventWriter {
+	if format == EventJSONL {
+		return &jsonEventWriter{
+			encoder: json.NewEncoder(w),
+		}
+	}
+	return &textEventWriter{
+		writer: w,
+	}
+}
+
+func (w *textEventWriter) Write(e *Event) error {
+	_, err := fmt.Fprintln(w.writer, e.String())
+	return err
+}
+
+func (w *textEventWriter) Flush() error {
+	return nil
+}
+
+func (w *jsonEventWriter) Write(e *Event) error {
+	return w.encoder.Encode(e)
+}
+
+func (w *jsonEventWriter) Flush() error {
+	return nil
+}
+
+// nodeState is the state of a node in the previous frame to detect events
+type nodeState struct {
+	enable         bool
+	component      int
+	seedLinkStatus int
+	authStatus     int
+}
+
+// eventDetector compares the current frame with the previous frame
+type eventDetector struct {
+	states  map[string]nodeState
+	oneWays map[edgeKey]bool
+	// components is the count of enabled members for each component in the previous frame
+	components map[int]int
+}
+
+type edgeKey struct {
+	from string
+	to   string
+}
+
+func newEventDetector() *eventDetector {
+	return &eventDetector{
+		states:     make(map[string]nodeState),
+		oneWays:    make(map[edgeKey]bool),
+		components: make(map[int]int),
+	}
+}
+
+// SetEventWriter sets the writer to output events detected in each frame
+func (s *Model2D) SetEventWriter(writer EventWriter) {
+	s.eventWriter = writer
+}
+
+// detectEvents finds events by the difference from the previous frame and flashes affected nodes
+func (s *Model2D) detectEvents(current *time.Time) error {
+	d := s.detector
+	events := make([]*Event, 0)
+	add := func(kind EventKind, nids []string, detail string) {
+		sort.Strings(nids)
+		events = append(events, &Event{
+			Time:   *current,
+			Kind:   kind,
+			Nids:   nids,
+			Detail: detail,
+		})
+	}
+
+	// events of each node
+	for nid, node := range s.nodes {
+		prev, existed := d.states[nid]
+		switch {
+		case node.enable && (!existed || !prev.enable):
+			add(EventJoin, []string{nid}, "")
+		case !node.enable && existed && prev.enable:
+			add(EventTimeout, []string{nid}, "")
+		}
+		if !node.enable {
+			continue
+		}
+		if existed && prev.enable && prev.seedLinkStatus == LinkStatusOnline && node.seedLinkStatus != LinkStatusOnline {
+			add(EventSeedLost, []string{nid}, "")
+		}
+		if node.authStatus == AuthStatusFailure && (!existed || prev.authStatus != AuthStatusFailure) {
+			add(EventAuthFailure, []string{nid}, "")
+		}
+	}
+
+	// links became one-way
+	oneWays := make(map[edgeKey]bool)
+	for nid, node := range s.nodes {
+		if !node.enable {
+			continue
+		}
+		for _, pairNid := range node.links {
+			pair, ok := s.nodes[pairNid]
+			if !ok || !pair.enable || pair.hasLink(nid) {
+				continue
+			}
+			key := edgeKey{nid, pairNid}
+			oneWays[key] = true
+			if !d.oneWays[key] {
+				add(EventOneWayLink, []string{nid, pairNid}, nid+" -> "+pairNid)
+			}
+		}
+	}
+
+	// groups split or merged, decided by overlap of members between frames
+	components := make(map[int]int)
+	for _, node := range s.nodes {
+		if node.enable {
+			components[node.component]++
+		}
+	}
+	splits := make(map[int]map[int]bool)
+	merges := make(map[int]map[int]bool)
+	for _, node := range s.nodes {
+		prev, existed := d.states[node.nid]
+		if !node.enable || !existed || !prev.enable {
+			continue
+		}
+		if d.components[prev.component] >= s.minGroupSize && components[node.component] >= s.minGroupSize {
+			if splits[prev.component] == nil {
+				splits[prev.component] = make(map[int]bool)
+			}
+			splits[prev.component][node.component] = true
+			if merges[node.component] == nil {
+				merges[node.component] = make(map[int]bool)
+			}
+			merges[node.component][prev.component] = true
+		}
+	}
+	for prevComponent, to := range splits {
+		if len(to) < 2 {
+			continue
+		}
+		nids := make([]string, 0)
+		for nid, prev := range d.states {
+			if node, ok := s.nodes[nid]; ok && node.enable && prev.enable && prev.component == prevComponent {
+				nids = append(nids, nid)
+			}
+		}
+		add(EventGroupSplit, nids, fmt.Sprintf("into %d groups", len(to)))
+	}
+	for component, from := range merges {
+		if len(from) < 2 {
+			continue
+		}
+		nids := make([]string, 0)
+		for nid, node := range s.nodes {
+			if node.enable && node.component == component {
+				nids = append(nids, nid)
+			}
+		}
+		add(EventGroupMerge, nids, fmt.Sprintf("from %d groups", len(from)))
+	}
+
+	// keep the current frame for the next
+	d.states = make(map[string]nodeState)
+	for nid, node := range s.nodes {
+		d.states[nid] = nodeState{
+			enable:         node.enable,
+			component:      node.component,
+			seedLinkStatus: node.seedLinkStatus,
+			authStatus:     node.authStatus,
+		}
+	}
+	d.oneWays = oneWays
+	d.components = components
+
+	// output events in a stable order
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Kind != events[j].Kind {
+			return events[i].Kind < events[j].Kind
+		}
+		return strings.Join(events[i].Nids, ",") < strings.Join(events[j].Nids, ",")
+	})
+	for _, e := range events {
+		for _, nid := range e.Nids {
+			s.nodes[nid].flash = *current
+		}
+		if s.eventWriter != nil {
+			if err := s.eventWriter.Write(e); err != nil {
+				return err
+			}
+		}
+	}
+	s.events = append(s.events, events...)
+	return nil
+}
+
+// isFlashing returns true if the node should be drawn by the event color, it blinks each second
+func isFlashing(node *Node, current time.Time) bool {
+	if node.flash.IsZero() {
+		return false
+	}
+	elapsed := current.Sub(node.flash)
+	return elapsed >= 0 && elapsed < flashDuration && (elapsed/time.Second)%2 == 0
+}
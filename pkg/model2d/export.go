@@ -0,0 +1,176 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MetricsFormat is the format to write metrics
+type MetricsFormat int
+
+const (
+	// MetricsCSV writes a line of comma separated values for each second after the header
+	MetricsCSV MetricsFormat = iota
+	// MetricsJSONL writes a JSON object for each second (JSON Lines)
+	MetricsJSONL
+)
+
+var metricsFormatNames = []string{
+	"csv",
+	"jsonl",
+}
+
+// ParseMetricsFormat gets the format of metrics by the name
+func ParseMetricsFormat(name string) (MetricsFormat, error) {
+	for idx, v := range metricsFormatNames {
+		if v == name {
+			return MetricsFormat(idx), nil
+		}
+	}
+	return MetricsCSV, fmt.Errorf("format should be one of %s: %s",
+		strings.Join(metricsFormatNames, ", "), name)
+}
+
+func (f MetricsFormat) String() string {
+	return metricsFormatNames[f]
+}
+
+// MetricsWriter writes metrics of each second as a time series
+type MetricsWriter interface {
+	Write(*Metrics) error
+	Flush() error
+}
+
+type csvMetricsWriter struct {
+	writer      *csv.Writer
+	wroteHeader bool
+}
+
+type jsonMetricsWriter struct {
+	encoder *json.Encoder
+}
+
+var csvHeader = []string{
+	"time",
+	"nodes",
+	"known_nodes",
+	"vouched_nodes",
+	"groups",
+	"largest_group",
+	"group_sizes",
+	"isolated_nodes",
+	"group_members",
+	"mutual_links",
+	"one_way_links",
+	"average_degree",
+	"seed_connected",
+	"onlyone",
+	"required_2d",
+	"established_2d",
+	"established_2d_rate",
+	"unknown_records",
+	"bad_records",
+	"delivered_packets",
+	"incomplete_packets",
+	"packet_hops",
+	"shortest_packet_hops",
+	"publishes",
+	"deliveries",
+	"map_operations",
+}
+
+// NewMetricsWriter makes a writer for the format
+func NewMetricsWriter(w io.Writer, format MetricsFormat) MetricsWriter {
+	if format == MetricsJSONL {
+		return &jsonMetricsWriter{
+			encoder: json.NewEncoder(w),
+		}
+	}
+	return &csvMetricsWriter{
+		writer: csv.NewWriter(w),
+	}
+}
+
+func (w *csvMetricsWriter) Write(m *Metrics) error {
+	if !w.wroteHeader {
+		if err := w.writer.Write(csvHeader); err != nil {
+			return err
+		}
+		w.wroteHeader = true
+	}
+
+	// group sizes are joined by semicolon to be kept in one column
+	sizes := make([]string, len(m.GroupSizes))
+	for i, size := range m.GroupSizes {
+		sizes[i] = strconv.Itoa(size)
+	}
+
+	// members are written like 1=nid,nid;2=nid
+	members := make([]string, len(m.Memberships))
+	for i, membership := range m.Memberships {
+		members[i] = strconv.Itoa(membership.Group) + "=" + strings.Join(membership.Nids, ",")
+	}
+
+	return w.writer.Write([]string{
+		m.Time.Format(time.RFC3339),
+		strconv.Itoa(m.Nodes),
+		strconv.Itoa(m.KnownNodes),
+		strconv.Itoa(m.VouchedNodes),
+		strconv.Itoa(m.Groups),
+		strconv.Itoa(m.LargestGroup),
+		strings.Join(sizes, ";"),
+		strconv.Itoa(m.IsolatedNodes),
+		strings.Join(members, ";"),
+		strconv.Itoa(m.MutualLinks),
+		strconv.Itoa(m.OneWayLinks),
+		strconv.FormatFloat(m.AverageDegree, 'f', 3, 64),
+		strconv.Itoa(m.SeedConnected),
+		strconv.Itoa(m.Onlyone),
+		strconv.Itoa(m.Required2D),
+		strconv.Itoa(m.Established2D),
+		strconv.FormatFloat(m.Established2DRate, 'f', 2, 64),
+		strconv.Itoa(m.UnknownRecords),
+		strconv.Itoa(m.BadRecords),
+		strconv.Itoa(m.DeliveredPackets),
+		strconv.Itoa(m.IncompletePackets),
+		strconv.Itoa(m.PacketHops),
+		strconv.Itoa(m.ShortestPacketHops),
+		strconv.Itoa(m.Publishes),
+		strconv.Itoa(m.Deliveries),
+		strconv.Itoa(m.MapOperations),
+	})
+}
+
+func (w *csvMetricsWriter) Flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+func (w *jsonMetricsWriter) Write(m *Metrics) error {
+	return w.encoder.Encode(m)
+}
+
+func (w *jsonMetricsWriter) Flush() error {
+	return nil
+}
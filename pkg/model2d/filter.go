@@ -0,0 +1,457 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// FilterMode decides how nodes not matching the filter are drawn
+type FilterMode int
+
+const (
+	// FilterDim draws nodes not matching the filter pale
+	FilterDim FilterMode = iota
+	// FilterHide does not draw nodes not matching the filter and their links
+	FilterHide
+	filterModeCount
+)
+
+var filterModeNames = []string{
+	"dim",
+	"hide",
+}
+
+// ParseFilterMode gets the filter mode by the name
+func ParseFilterMode(name string) (FilterMode, error) {
+	for idx, v := range filterModeNames {
+		if v == name {
+			return FilterMode(idx), nil
+		}
+	}
+	return FilterDim, fmt.Errorf("filter mode should be one of %s: %s",
+		strings.Join(filterModeNames, ", "), name)
+}
+
+func (m FilterMode) String() string {
+	return filterModeNames[m]
+}
+
+// filterDimRate is the rate of the color of nodes not matching the filter mixed into the background
+const filterDimRate = 0.15
+
+// filterStatusNames are names usable as values of status fields in filter expressions
+var filterStatusNames = map[string]map[string]float64{
+	"seed": {"offline": LinkStatusOffline, "connecting": LinkStatusConnecting, "online": LinkStatusOnline, "closing": LinkStatusClosing},
+	"node": {"offline": LinkStatusOffline, "connecting": LinkStatusConnecting, "online": LinkStatusOnline, "closing": LinkStatusClosing},
+	"auth": {"none": AuthStatusNone, "success": AuthStatusSuccess, "failure": AuthStatusFailure},
+}
+
+// Filter is a parsed filter expression selecting nodes, like `group==1 && degree>5`.
+// A word without an operator matches nodes having nid starting with it.
+type Filter struct {
+	expr  string
+	match func(node *Node, current time.Time) bool
+}
+
+// ParseFilter parses the filter expression, the filter is nil for an empty expression
+func ParseFilter(expr string) (*Filter, error) {
+	if len(strings.TrimSpace(expr)) == 0 {
+		return nil, nil
+	}
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	match, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in the filter: %s", p.tokens[p.pos].text, expr)
+	}
+	return &Filter{
+		expr:  expr,
+		match: match,
+	}, nil
+}
+
+func (f *Filter) String() string {
+	return f.expr
+}
+
+// Match returns true if the node matches the filter at the time
+func (f *Filter) Match(node *Node, current time.Time) bool {
+	return f.match(node, current)
+}
+
+type filterToken struct {
+	text string
+	// quoted is true for a string in quotes, it is not an operator even if the text looks like one
+	quoted bool
+}
+
+var filterOperators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")"}
+
+func (t filterToken) isOperator() bool {
+	return !t.quoted && contains(filterOperators, t.text)
+}
+
+func tokenizeFilter(expr string) ([]filterToken, error) {
+	tokens := make([]filterToken, 0)
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		if unicode.IsSpace(r) {
+			i++
+			continue
+		}
+
+		if r == '"' || r == '\'' {
+			end := i + 1
+			for end < len(runes) && runes[end] != r {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("unclosed quote in the filter: %s", expr)
+			}
+			tokens = append(tokens, filterToken{text: string(runes[i+1 : end]), quoted: true})
+			i = end + 1
+			continue
+		}
+
+		operator := ""
+		for _, op := range filterOperators {
+			if strings.HasPrefix(string(runes[i:]), op) {
+				operator = op
+				break
+			}
+		}
+		if len(operator) != 0 {
+			tokens = append(tokens, filterToken{text: operator})
+			i += len([]rune(operator))
+			continue
+		}
+
+		start := i
+		for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune("&|=!<>()\"'", runes[i]) {
+			i++
+		}
+		if start == i {
+			return nil, fmt.Errorf("unexpected %q in the filter: %s", r, expr)
+		}
+		tokens = append(tokens, filterToken{text: string(runes[start:i])})
+	}
+	return tokens, nil
+}
+
+// filterParser parses tokens by recursive descent, && binds tighter than ||
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+type filterFunc func(node *Node, current time.Time) bool
+
+func (p *filterParser) peek(operator string) bool {
+	return p.pos < len(p.tokens) && !p.tokens[p.pos].quoted && p.tokens[p.pos].text == operator
+}
+
+func (p *filterParser) parseOr() (filterFunc, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek("||") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(node *Node, current time.Time) bool {
+			return l(node, current) || right(node, current)
+		}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterFunc, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek("&&") {
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(node *Node, current time.Time) bool {
+			return l(node, current) && right(node, current)
+		}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (filterFunc, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of the filter")
+	}
+
+	if p.peek("!") {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(node *Node, current time.Time) bool {
+			return !operand(node, current)
+		}, nil
+	}
+
+	if p.peek("(") {
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peek(")") {
+			return nil, fmt.Errorf("missing ) in the filter")
+		}
+		p.pos++
+		return inner, nil
+	}
+
+	name := p.tokens[p.pos]
+	if name.isOperator() {
+		return nil, fmt.Errorf("unexpected %q in the filter", name.text)
+	}
+	p.pos++
+
+	operator := ""
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.peek(op) {
+			operator = op
+			break
+		}
+	}
+	// a word without an operator is the prefix of nid
+	if len(operator) == 0 {
+		return func(node *Node, current time.Time) bool {
+			return strings.HasPrefix(node.nid, name.text)
+		}, nil
+	}
+	p.pos++
+
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("missing the value of %s in the filter", name.text)
+	}
+	value := p.tokens[p.pos]
+	if value.isOperator() {
+		return nil, fmt.Errorf("unexpected %q in the filter", value.text)
+	}
+	p.pos++
+	return newComparison(name.text, operator, value.text), nil
+}
+
+// newComparison makes the function comparing the field of nodes with the value,
+// numbers are compared as numbers and others are compared as strings,
+// a missing field or a number compared with a non-number value matches only != as it is not equal to the value
+func newComparison(field, operator, value string) filterFunc {
+	number, err := strconv.ParseFloat(value, 64)
+	isNumber := err == nil
+	if names, ok := filterStatusNames[field]; ok {
+		if status, ok := names[value]; ok {
+			number = status
+			isNumber = true
+		}
+	}
+	if value == "true" || value == "false" {
+		number = 0.0
+		if value == "true" {
+			number = 1.0
+		}
+		isNumber = true
+	}
+
+	return func(node *Node, current time.Time) bool {
+		v, ok := filterField(node, field, current)
+		if !ok {
+			return operator == "!="
+		}
+		var c int
+		switch v := v.(type) {
+		case float64:
+			if !isNumber {
+				return operator == "!="
+			}
+			c = compareFloat(v, number)
+		case string:
+			c = strings.Compare(v, value)
+		default:
+			return operator == "!="
+		}
+
+		switch operator {
+		case "==":
+			return c == 0
+		case "!=":
+			return c != 0
+		case "<":
+			return c < 0
+		case "<=":
+			return c <= 0
+		case ">":
+			return c > 0
+		default:
+			return c >= 0
+		}
+	}
+}
+
+func compareFloat(a, b float64) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
+
+// filterField gets the value of the field as float64 or string, attributes set by handlers are used
+// for names which are not fields of nodes
+func filterField(node *Node, field string, current time.Time) (interface{}, bool) {
+	boolValue := func(b bool) float64 {
+		if b {
+			return 1.0
+		}
+		return 0.0
+	}
+
+	switch field {
+	case "nid":
+		return node.nid, true
+	case "group":
+		return float64(node.group), true
+	case "degree":
+		return float64(len(node.links)), true
+	case "age":
+		return current.Sub(node.firstSeen).Seconds(), true
+	case "staleness":
+		return current.Sub(node.timestamp).Seconds(), true
+	case "seed":
+		return float64(node.seedLinkStatus), true
+	case "node":
+		return float64(node.nodeLinkStatus), true
+	case "auth":
+		return float64(node.authStatus), true
+	case "onlyone":
+		return boolValue(node.isOnlyone), true
+	case "vouched":
+		return boolValue(node.vouched), true
+	case "x":
+		return node.x, true
+	case "y":
+		return node.y, true
+	}
+
+	value, ok := node.Attribute(field)
+	if !ok {
+		return nil, false
+	}
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case bool:
+		return boolValue(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return fmt.Sprint(value), true
+}
+
+// SetFilter sets the filter of nodes and how nodes not matching it are drawn, nil filter matches all nodes
+func (s *Model2D) SetFilter(filter *Filter, mode FilterMode) {
+	s.filter = filter
+	s.filterMode = mode
+}
+
+func (s *Model2D) setupFilterKeys() {
+	s.gl.OnKey('f', func() {
+		expr := ""
+		if s.filter != nil {
+			expr = s.filter.String()
+		}
+		s.gl.Prompt("filter", expr, func(expr string) {
+			filter, err := ParseFilter(expr)
+			if err != nil {
+				log.Printf("keep the filter: %v", err)
+				return
+			}
+			s.filter = filter
+			log.Printf("filter: %s", expr)
+		})
+	})
+	s.gl.OnKey('m', func() {
+		s.filterMode = (s.filterMode + 1) % filterModeCount
+		log.Printf("filter mode: %s", s.filterMode)
+	})
+}
+
+// isHidden returns true if the node is not drawn by the filter
+func (s *Model2D) isHidden(node *Node) bool {
+	return s.filterMode == FilterHide && node.filteredOut
+}
+
+// applyFilter marks nodes not matching the filter and gets nodes to be drawn, hidden nodes are excluded
+func (s *Model2D) applyFilter(current *time.Time) map[string]*Node {
+	s.filterMatched = 0
+	for _, node := range s.nodes {
+		node.filteredOut = s.filter != nil && !s.filter.Match(node, *current)
+		if node.enable && !node.filteredOut {
+			s.filterMatched++
+		}
+	}
+	if s.filter == nil || s.filterMode != FilterHide {
+		return s.nodes
+	}
+
+	visible := make(map[string]*Node, len(s.nodes))
+	for nid, node := range s.nodes {
+		if !node.filteredOut {
+			visible[nid] = node
+		}
+	}
+	return visible
+}
@@ -0,0 +1,125 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseFilter(t *testing.T) {
+	current := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	node := &Node{
+		nid:            "0123abcd",
+		group:          1,
+		links:          []string{"a", "b", "c"},
+		firstSeen:      current.Add(-30 * time.Second),
+		timestamp:      current.Add(-2 * time.Second),
+		seedLinkStatus: LinkStatusOnline,
+		nodeLinkStatus: LinkStatusConnecting,
+		authStatus:     AuthStatusSuccess,
+		isOnlyone:      true,
+		x:              0.5,
+		y:              -0.25,
+	}
+	node.SetAttribute("region", "tokyo")
+	node.SetAttribute("load", 3)
+
+	tests := []struct {
+		expr  string
+		match bool
+	}{
+		{"0123", true},
+		{"4567", false},
+		{"nid==0123abcd", true},
+		{"group==1", true},
+		{"group!=1", false},
+		{"degree>2", true},
+		{"degree>=4", false},
+		{"age>29 && staleness<3", true},
+		{"x<=0.5 && y<0", true},
+		// && binds tighter than ||
+		{"group==2 || degree==3 && x>0", true},
+		{"group==1 || degree==0 && x<0", true},
+		{"group==2 || degree==0 && x>0", false},
+		{"(group==1 || degree==0) && x<0", false},
+		{"!(group==2) && !vouched", true},
+		{"!group==1", false},
+		{"seed==online", true},
+		{"node==online", false},
+		{"node==connecting && auth==success", true},
+		{"auth==failure", false},
+		{"onlyone==true && vouched==false", true},
+		{"region==tokyo", true},
+		{"region=='osaka'", false},
+		{"load>2", true},
+		// missing attributes and values of other types are not equal
+		{"unknown==1", false},
+		{"unknown!=1", true},
+		{"unknown<1", false},
+		{"!(unknown==1)", true},
+		{"region==1", false},
+		{"region!=1", true},
+		{"load==heavy", false},
+		{"load!=heavy", true},
+		{"load>heavy", false},
+		// quoted operators are values
+		{"region=='&&'", false},
+		{"nid!=\"||\"", true},
+	}
+	for _, tt := range tests {
+		filter, err := ParseFilter(tt.expr)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.expr, err)
+			continue
+		}
+		if filter.String() != tt.expr {
+			t.Errorf("%s: String() = %s", tt.expr, filter)
+		}
+		if match := filter.Match(node, current); match != tt.match {
+			t.Errorf("%s: Match() = %v, want %v", tt.expr, match, tt.match)
+		}
+	}
+}
+
+func TestParseFilterEmpty(t *testing.T) {
+	for _, expr := range []string{"", "  "} {
+		filter, err := ParseFilter(expr)
+		if filter != nil || err != nil {
+			t.Errorf("%q: ParseFilter() = %v, %v, want nil", expr, filter, err)
+		}
+	}
+}
+
+func TestParseFilterError(t *testing.T) {
+	for _, expr := range []string{
+		"group==",
+		"&",
+		"group==1 & degree>2",
+		"region=='tokyo",
+		"(group==1",
+		"group==1)",
+		"group==1 &&",
+		"|| group==1",
+		"group== &&",
+		"!",
+	} {
+		if _, err := ParseFilter(expr); err == nil {
+			t.Errorf("%q: expected an error", expr)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"log"
+	"time"
+
+	"github.com/llamerada-jp/colonio-simulator-view/pkg/utils"
+)
+
+// focusTrailLifetime is the time to keep past positions of the focused node
+const focusTrailLifetime = 30 * time.Second
+
+// focusDrawer is implemented by drawers which can move the camera to the focused node and highlight it
+type focusDrawer interface {
+	// setFocus moves the camera to the node, nil node resets the camera
+	setFocus(node *Node)
+	drawFocus(gl *utils.GL, node *Node, nodes map[string]*Node, trail []trailPoint, current *time.Time)
+}
+
+// SetFocus selects the node and makes the camera follow it, empty nid selects nothing
+func (s *Model2D) SetFocus(nid string) {
+	s.selected = nid
+	s.focus = len(nid) != 0
+}
+
+func (s *Model2D) setupFocusKeys() {
+	if _, ok := s.drawer.(focusDrawer); !ok {
+		return
+	}
+	s.gl.OnKey('g', func() {
+		s.focus = !s.focus
+		log.Printf("focus on the selected node: %t", s.focus)
+	})
+}
+
+// updateCamera moves the camera of the drawer to the selected node while focusing,
+// the camera stays at the last position while the node is not drawn and is reset if no node is selected
+func (s *Model2D) updateCamera(nodes map[string]*Node) {
+	drawer, ok := s.drawer.(focusDrawer)
+	if !ok {
+		return
+	}
+	if !s.focus || len(s.selected) == 0 {
+		drawer.setFocus(nil)
+		return
+	}
+	if node, ok := nodes[s.selected]; ok && node.enable {
+		drawer.setFocus(node)
+	}
+}
+
+func (s *Model2D) drawFocus(nodes map[string]*Node, current *time.Time) {
+	drawer, ok := s.drawer.(focusDrawer)
+	if !ok || !s.focus {
+		return
+	}
+	if node, ok := nodes[s.selected]; ok && node.enable {
+		drawer.drawFocus(s.gl, node, nodes, recentTrail(node, *current, focusTrailLifetime), current)
+	}
+}
+
+// trailColor gets the color of the trail fading out by the age of the point
+func (p *painter) trailColor(c utils.Color, t, current time.Time, lifetime time.Duration) utils.Color {
+	rate := 1.0 - float32(current.Sub(t))/float32(lifetime)
+	if rate < 0.0 {
+		rate = 0.0
+	}
+	return p.theme.Background.Mix(c, rate)
+}
+
+// drawFocus draws the trail of the node, and highlights links and required 2D peers of it
+func (s *Plane) drawFocus(gl *utils.GL, node *Node, nodes map[string]*Node, trail []trailPoint, current *time.Time) {
+	for i := 1; i < len(trail); i++ {
+		x1, y1 := s.convertCoordinate(trail[i-1].x, trail[i-1].y)
+		x2, y2 := s.convertCoordinate(trail[i].x, trail[i].y)
+		gl.SetColor(s.trailColor(s.theme.Focus, trail[i].time, *current, focusTrailLifetime))
+		gl.Line3(x1, y1, -0.9, x2, y2, -0.9)
+	}
+
+	gl.SetColor(s.theme.Focus)
+	for _, nid := range node.links {
+		if pair, ok := nodes[nid]; ok && pair.enable {
+			s.line(gl, node, pair, -0.85, -0.85)
+		}
+	}
+	for _, nid := range node.required2D {
+		if pair, ok := nodes[nid]; ok && pair.enable {
+			x, y := s.convertCoordinate(pair.x, pair.y)
+			gl.Box3(x, y, -1.0, 12.0)
+		}
+	}
+}
+
+// drawFocus draws the trail of the node, and highlights links and required 2D peers of it
+func (s *Sphere) drawFocus(gl *utils.GL, node *Node, nodes map[string]*Node, trail []trailPoint, current *time.Time) {
+	for i := 1; i < len(trail); i++ {
+		x1, y1, z1 := s.convertCoordinate(trail[i-1].x, trail[i-1].y)
+		x2, y2, z2 := s.convertCoordinate(trail[i].x, trail[i].y)
+		c := s.trailColor(s.theme.Focus, trail[i].time, *current, focusTrailLifetime)
+		gl.SetColor(s.reduceColorByZ(c, (z1+z2)/2.0))
+		gl.Line3(x1, y1, sphereFront(z1), x2, y2, sphereFront(z2))
+	}
+
+	x1, y1, z1 := s.convertCoordinate(node.x, node.y)
+	for _, nid := range node.links {
+		if pair, ok := nodes[nid]; ok && pair.enable {
+			x2, y2, z2 := s.convertCoordinate(pair.x, pair.y)
+			gl.SetColor(s.reduceColorByZ(s.theme.Focus, (z1+z2)/2.0))
+			gl.Line3(x1, y1, sphereFront(z1), x2, y2, sphereFront(z2))
+		}
+	}
+	for _, nid := range node.required2D {
+		if pair, ok := nodes[nid]; ok && pair.enable {
+			x, y, z := s.convertCoordinate(pair.x, pair.y)
+			gl.SetColor(s.reduceColorByZ(s.theme.Focus, z))
+			gl.Box3(x, y, z, 12.0)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Connectivity decides which links connect nodes into a group
+type Connectivity int
+
+const (
+	// ConnectivityWeak connects nodes by links in either direction
+	ConnectivityWeak Connectivity = iota
+	// ConnectivityMutual connects nodes only by links in both directions
+	ConnectivityMutual
+)
+
+var connectivityNames = []string{
+	"weak",
+	"mutual",
+}
+
+// ParseConnectivity gets the connectivity by the name
+func ParseConnectivity(name string) (Connectivity, error) {
+	for idx, v := range connectivityNames {
+		if v == name {
+			return Connectivity(idx), nil
+		}
+	}
+	return ConnectivityWeak, fmt.Errorf("connectivity should be one of %s: %s",
+		strings.Join(connectivityNames, ", "), name)
+}
+
+func (c Connectivity) String() string {
+	return connectivityNames[c]
+}
+
+// SetConnectivity sets which links connect nodes into a group
+func (s *Model2D) SetConnectivity(connectivity Connectivity) {
+	s.connectivity = connectivity
+}
+
+// SetMinGroupSize sets the minimum count of members to be regarded as a group,
+// nodes in smaller groups are drawn as isolated nodes
+func (s *Model2D) SetMinGroupSize(size int) {
+	s.minGroupSize = size
+}
+
+// unionFind is a disjoint set of indexes without recursion
+type unionFind struct {
+	parent []int
+	size   []int
+}
+
+func newUnionFind(n int) *unionFind {
+	u := &unionFind{
+		parent: make([]int, n),
+		size:   make([]int, n),
+	}
+	for i := range u.parent {
+		u.parent[i] = i
+		u.size[i] = 1
+	}
+	return u
+}
+
+func (u *unionFind) find(x int) int {
+	for u.parent[x] != x {
+		// path halving
+		u.parent[x] = u.parent[u.parent[x]]
+		x = u.parent[x]
+	}
+	return x
+}
+
+func (u *unionFind) union(a, b int) {
+	ra := u.find(a)
+	rb := u.find(b)
+	if ra == rb {
+		return
+	}
+	if u.size[ra] < u.size[rb] {
+		ra, rb = rb, ra
+	}
+	u.parent[rb] = ra
+	u.size[ra] += u.size[rb]
+}
+
+// setGroupNumber assigns the group ID to each enabled node, ID 0 is for very small groups.
+// IDs are kept across frames by matching groups to the previous frame by count of common members.
+func (s *Model2D) setGroupNumber() {
+	// index nodes in the order of nid to make the result deterministic
+	nodes := make([]*Node, 0, len(s.nodes))
+	for _, node := range s.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].nid < nodes[j].nid
+	})
+	index := make(map[string]int, len(nodes))
+	for i, node := range nodes {
+		index[node.nid] = i
+	}
+
+	uf := newUnionFind(len(nodes))
+	for i, node := range nodes {
+		if !node.enable {
+			continue
+		}
+		for _, nid := range node.links {
+			j, ok := index[nid]
+			if !ok || !nodes[j].enable {
+				continue
+			}
+			if s.connectivity == ConnectivityMutual && !nodes[j].hasLink(node.nid) {
+				continue
+			}
+			uf.union(i, j)
+		}
+	}
+
+	// collect members of each component, ordered by member count
+	members := make(map[int][]*Node)
+	roots := make([]int, 0)
+	for i, node := range nodes {
+		if !node.enable {
+			continue
+		}
+		root := uf.find(i)
+		if _, ok := members[root]; !ok {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], node)
+	}
+	sort.SliceStable(roots, func(i, j int) bool {
+		return len(members[roots[i]]) > len(members[roots[j]])
+	})
+
+	// sizes of components regarded as groups, smaller components are isolated nodes
+	s.groupSizes = s.groupSizes[:0]
+	for _, root := range roots {
+		if len(members[root]) >= s.minGroupSize {
+			s.groupSizes = append(s.groupSizes, len(members[root]))
+		}
+	}
+
+	// match components to groups of the previous frame, node.group still has the previous ID here
+	type match struct {
+		component int
+		group     int
+		overlap   int
+	}
+	matches := make([]match, 0)
+	for idx, root := range roots {
+		if len(members[root]) < s.minGroupSize {
+			continue
+		}
+		overlaps := make(map[int]int)
+		for _, node := range members[root] {
+			if node.group != 0 {
+				overlaps[node.group]++
+			}
+		}
+		for group, overlap := range overlaps {
+			matches = append(matches, match{idx, group, overlap})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].overlap != matches[j].overlap {
+			return matches[i].overlap > matches[j].overlap
+		}
+		if matches[i].component != matches[j].component {
+			return matches[i].component < matches[j].component
+		}
+		return matches[i].group < matches[j].group
+	})
+
+	assign := make([]int, len(roots))
+	used := make(map[int]bool)
+	for _, m := range matches {
+		if assign[m.component] != 0 || used[m.group] {
+			continue
+		}
+		assign[m.component] = m.group
+		used[m.group] = true
+	}
+
+	// new groups get the smallest unused IDs in order of member count
+	next := 1
+	for idx, root := range roots {
+		if assign[idx] != 0 || len(members[root]) < s.minGroupSize {
+			continue
+		}
+		for used[next] {
+			next++
+		}
+		assign[idx] = next
+		used[next] = true
+	}
+
+	for _, node := range nodes {
+		node.group = 0
+		node.component = 0
+	}
+	for idx, root := range roots {
+		for _, node := range members[root] {
+			node.group = assign[idx]
+			node.component = idx + 1
+		}
+	}
+}
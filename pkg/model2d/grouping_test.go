@@ -0,0 +1,196 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// newGroupingModel makes the model having enabled nodes with links
+func newGroupingModel(links map[string][]string, connectivity Connectivity) *Model2D {
+	s := &Model2D{
+		nodes:        make(map[string]*Node),
+		connectivity: connectivity,
+		minGroupSize: 2,
+	}
+	setGroupingLinks(s, links)
+	return s
+}
+
+func setGroupingLinks(s *Model2D, links map[string][]string) {
+	for _, node := range s.nodes {
+		node.enable = false
+		node.links = nil
+	}
+	for nid, pairs := range links {
+		node, ok := s.nodes[nid]
+		if !ok {
+			node = &Node{nid: nid}
+			s.nodes[nid] = node
+		}
+		node.enable = true
+		node.links = pairs
+	}
+}
+
+// groupsOf gets nids of each group by the group ID
+func groupsOf(s *Model2D) map[int][]string {
+	groups := make(map[int][]string)
+	for nid, node := range s.nodes {
+		if node.enable {
+			groups[node.group] = append(groups[node.group], nid)
+		}
+	}
+	for _, nids := range groups {
+		sort.Strings(nids)
+	}
+	return groups
+}
+
+func TestUnionFind(t *testing.T) {
+	u := newUnionFind(6)
+	u.union(0, 1)
+	u.union(2, 3)
+	u.union(1, 3)
+	if u.find(0) != u.find(2) || u.find(4) == u.find(0) || u.find(4) == u.find(5) {
+		t.Errorf("unexpected sets: %v", u.parent)
+	}
+	if u.size[u.find(0)] != 4 {
+		t.Errorf("size = %d, want 4", u.size[u.find(0)])
+	}
+}
+
+func TestSetGroupNumber(t *testing.T) {
+	tests := []struct {
+		name         string
+		connectivity Connectivity
+		links        map[string][]string
+		groups       map[int][]string
+		sizes        []int
+	}{
+		{
+			name:         "weak",
+			connectivity: ConnectivityWeak,
+			links: map[string][]string{
+				"a": {"b"}, "b": {}, "c": {"b"},
+				"d": {"e"}, "e": {"d"},
+				"f": {},
+			},
+			groups: map[int][]string{1: {"a", "b", "c"}, 2: {"d", "e"}, 0: {"f"}},
+			sizes:  []int{3, 2},
+		},
+		{
+			name:         "mutual",
+			connectivity: ConnectivityMutual,
+			links: map[string][]string{
+				"a": {"b"}, "b": {"a"}, "c": {"b"},
+				"d": {"e"}, "e": {"d", "f"}, "f": {"d", "e"},
+			},
+			groups: map[int][]string{1: {"d", "e", "f"}, 2: {"a", "b"}, 0: {"c"}},
+			sizes:  []int{3, 2},
+		},
+		{
+			name:         "links to unknown nodes",
+			connectivity: ConnectivityWeak,
+			links:        map[string][]string{"a": {"x"}, "b": {"x"}},
+			groups:       map[int][]string{0: {"a", "b"}},
+			sizes:        []int{},
+		},
+	}
+	for _, tt := range tests {
+		s := newGroupingModel(tt.links, tt.connectivity)
+		s.setGroupNumber()
+		if groups := groupsOf(s); !reflect.DeepEqual(groups, tt.groups) {
+			t.Errorf("%s: groups = %v, want %v", tt.name, groups, tt.groups)
+		}
+		if !reflect.DeepEqual(append([]int{}, s.groupSizes...), tt.sizes) {
+			t.Errorf("%s: sizes = %v, want %v", tt.name, s.groupSizes, tt.sizes)
+		}
+	}
+}
+
+func TestSetGroupNumberAcrossFrames(t *testing.T) {
+	tests := []struct {
+		name   string
+		first  map[string][]string
+		second map[string][]string
+		groups map[int][]string
+	}{
+		{
+			// the merged group keeps the ID of the group having more members in it
+			name: "merge",
+			first: map[string][]string{
+				"a": {"b"}, "b": {"a"},
+				"c": {"d"}, "d": {"e"}, "e": {"c"},
+			},
+			second: map[string][]string{
+				"a": {"b"}, "b": {"c"},
+				"c": {"d"}, "d": {"e"}, "e": {"c"},
+			},
+			groups: map[int][]string{1: {"a", "b", "c", "d", "e"}},
+		},
+		{
+			// the larger part keeps the ID and the other part gets a new ID
+			name: "split",
+			first: map[string][]string{
+				"a": {"b"}, "b": {"c"}, "c": {"d"}, "d": {"e"}, "e": {"a"},
+			},
+			second: map[string][]string{
+				"a": {"b"}, "b": {"a"},
+				"c": {"d"}, "d": {"e"}, "e": {"c"},
+			},
+			groups: map[int][]string{1: {"c", "d", "e"}, 2: {"a", "b"}},
+		},
+		{
+			// IDs follow members even if the order of sizes is swapped
+			name: "renumbering",
+			first: map[string][]string{
+				"a": {"b"}, "b": {"c"}, "c": {"a"},
+				"d": {"e"}, "e": {"d"},
+			},
+			second: map[string][]string{
+				"a": {"b"}, "b": {"a"}, "c": {},
+				"d": {"e"}, "e": {"f"}, "f": {"g"}, "g": {"d"},
+			},
+			groups: map[int][]string{1: {"a", "b"}, 2: {"d", "e", "f", "g"}, 0: {"c"}},
+		},
+		{
+			// the ID of the group disappeared is reused by the smallest unused ID
+			name: "new group",
+			first: map[string][]string{
+				"a": {"b"}, "b": {"c"}, "c": {"a"},
+				"d": {"e"}, "e": {"d"},
+			},
+			second: map[string][]string{
+				"d": {"e"}, "e": {"d"},
+				"f": {"g"}, "g": {"f"},
+			},
+			groups: map[int][]string{1: {"f", "g"}, 2: {"d", "e"}},
+		},
+	}
+	for _, tt := range tests {
+		s := newGroupingModel(tt.first, ConnectivityWeak)
+		s.setGroupNumber()
+		setGroupingLinks(s, tt.second)
+		s.setGroupNumber()
+		if groups := groupsOf(s); !reflect.DeepEqual(groups, tt.groups) {
+			t.Errorf("%s: groups = %v, want %v", tt.name, groups, tt.groups)
+		}
+	}
+}
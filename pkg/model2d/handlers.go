@@ -0,0 +1,134 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"sync"
+)
+
+// MessageHandler updates the node by a record of the message
+type MessageHandler struct {
+	// NewParam makes a pointer to decode the parameter of the record into,
+	// the parameter is not decoded if it is nil
+	NewParam func() interface{}
+	// Apply updates the node by the decoded parameter, param is nil if NewParam is nil
+	Apply func(node *Node, param interface{})
+}
+
+var (
+	handlersMutex sync.RWMutex
+	handlers      = make(map[string]MessageHandler)
+)
+
+// RegisterHandler registers the handler for records of the message, it replaces the registered handler
+func RegisterHandler(message string, handler MessageHandler) {
+	handlersMutex.Lock()
+	defer handlersMutex.Unlock()
+	handlers[message] = handler
+}
+
+func getHandler(message string) (MessageHandler, bool) {
+	handlersMutex.RLock()
+	defer handlersMutex.RUnlock()
+	handler, ok := handlers[message]
+	return handler, ok
+}
+
+// NID gets the node ID
+func (n *Node) NID() string {
+	return n.nid
+}
+
+// Attribute gets the value set by SetAttribute, it is for handlers out of this package
+func (n *Node) Attribute(key string) (interface{}, bool) {
+	value, ok := n.attributes[key]
+	return value, ok
+}
+
+// SetAttribute keeps the value in the node, it is for handlers out of this package
+func (n *Node) SetAttribute(key string, value interface{}) {
+	if n.attributes == nil {
+		n.attributes = make(map[string]interface{})
+	}
+	n.attributes[key] = value
+}
+
+func init() {
+	RegisterHandler(messageCurrentPosition, MessageHandler{
+		NewParam: func() interface{} {
+			return &ParameterCurrentPosition{}
+		},
+		Apply: func(node *Node, param interface{}) {
+			p := param.(*ParameterCurrentPosition)
+			node.x = p.Coordinate.X
+			node.y = p.Coordinate.Y
+		},
+	})
+
+	RegisterHandler(messageLinks, MessageHandler{
+		NewParam: func() interface{} {
+			return &ParameterLinks{}
+		},
+		Apply: func(node *Node, param interface{}) {
+			node.links = param.(*ParameterLinks).Nids
+		},
+	})
+
+	RegisterHandler(messageRouting1DRequired, MessageHandler{
+		NewParam: func() interface{} {
+			return &ParameterLinks{}
+		},
+		Apply: func(node *Node, param interface{}) {
+			node.required1D = param.(*ParameterLinks).Nids
+		},
+	})
+
+	RegisterHandler(messageRouting2DRequired, MessageHandler{
+		NewParam: func() interface{} {
+			return &ParameterRouting2DRequired{}
+		},
+		Apply: func(node *Node, param interface{}) {
+			p := param.(*ParameterRouting2DRequired)
+			node.required2D = make([]string, len(p.Nids))
+			idx := 0
+			for k := range p.Nids {
+				node.required2D[idx] = k
+				idx++
+			}
+		},
+	})
+
+	RegisterHandler(messageLinkStatus, MessageHandler{
+		NewParam: func() interface{} {
+			return &ParameterLinkStatus{}
+		},
+		Apply: func(node *Node, param interface{}) {
+			p := param.(*ParameterLinkStatus)
+			node.seedLinkStatus = p.Seed
+			node.nodeLinkStatus = p.Node
+			node.authStatus = p.Auth
+			node.isOnlyone = p.Onlyone
+			node.recordStatus(node.timestamp)
+		},
+	})
+
+	RegisterHandler(messageLeave, MessageHandler{
+		Apply: func(node *Node, param interface{}) {
+			node.left = true
+		},
+	})
+}
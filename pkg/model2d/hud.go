@@ -0,0 +1,156 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/llamerada-jp/colonio-simulator-view/pkg/utils"
+)
+
+const (
+	hudMargin     = 4
+	hudTimeFormat = "2006-01-02 15:04:05"
+	hudGroupLimit = 4
+	// timelineHeight is the height of the timeline bar in pixels
+	timelineHeight = 12
+)
+
+// drawHUD draws metrics of the current frame at the top-left of the window
+func (s *Model2D) drawHUD() {
+	m := s.metrics
+	if m == nil {
+		return
+	}
+
+	lines := []string{
+		m.Time.Format(hudTimeFormat),
+		fmt.Sprintf("nodes    %d/%d (vouched %d)", m.Nodes, m.KnownNodes, m.VouchedNodes),
+		fmt.Sprintf("groups   %d (largest %d)", m.Groups, m.LargestGroup),
+		groupSummary(m),
+		fmt.Sprintf("one-way  %d", m.OneWayLinks),
+		fmt.Sprintf("degree   %.2f", m.AverageDegree),
+		fmt.Sprintf("seed     %d  only-one %d", m.SeedConnected, m.Onlyone),
+		fmt.Sprintf("required %.1f%% (%d/%d)", m.Established2DRate, m.Established2D, m.Required2D),
+		s.convergence.String(),
+	}
+	if m.DeliveredPackets != 0 {
+		lines = append(lines, fmt.Sprintf("packets  %d hops %d (shortest %d) incomplete %d",
+			m.DeliveredPackets, m.PacketHops, m.ShortestPacketHops, m.IncompletePackets))
+	}
+	if m.Publishes != 0 || m.MapOperations != 0 {
+		lines = append(lines, fmt.Sprintf("pubsub   publish %d deliver %d map %d", m.Publishes, m.Deliveries, m.MapOperations))
+	}
+	if m.UnknownRecords != 0 {
+		lines = append(lines, fmt.Sprintf("unknown  %d records", m.UnknownRecords))
+	}
+	if m.BadRecords != 0 {
+		lines = append(lines, fmt.Sprintf("skipped  %d bad records", m.BadRecords))
+	}
+	if s.filter != nil {
+		lines = append(lines, fmt.Sprintf("filter   %s (%s %d/%d)", s.filter, s.filterMode, s.filterMatched, m.Nodes))
+	}
+	if s.diff != nil {
+		lines = append(lines, fmt.Sprintf("diff     %s -> %s", s.diff.From.Format(hudTimeFormat), s.diff.To.Format(hudTimeFormat)),
+			"         "+s.diff.Summary())
+	} else if len(s.diffMoments) == 1 {
+		lines = append(lines, "diff     mark another moment on the timeline")
+	} else if len(s.diffMoments) == 2 {
+		lines = append(lines, "diff     waiting for playback to pass the moments")
+	}
+	s.drawPanel(hudMargin, hudMargin, lines)
+	s.drawTimeline()
+}
+
+// drawTimeline draws the bar of the time range at the bottom of the scene with markers of events
+func (s *Model2D) drawTimeline() {
+	x, y, barWidth, span := s.timelineArea()
+	if span <= 0 || barWidth <= 0 {
+		return
+	}
+	position := func(t time.Time) int {
+		return x + int(float64(barWidth-1)*t.Sub(s.start).Seconds()/span)
+	}
+
+	s.gl.SetColor(s.theme.Panel)
+	s.gl.Rect(x, y, barWidth, timelineHeight)
+
+	s.gl.SetColor(s.theme.Event)
+	for _, e := range s.events {
+		s.gl.Rect(position(e.Time), y+2, 1, timelineHeight-4)
+	}
+
+	s.gl.SetColor(s.theme.Changed)
+	for _, m := range s.diffMoments {
+		s.gl.Rect(position(m.at(s.earliest))-1, y-2, 3, timelineHeight+4)
+	}
+
+	s.gl.SetColor(s.theme.Text)
+	s.gl.Rect(position(s.metrics.Time)-1, y, 3, timelineHeight)
+}
+
+// timelineArea gets the position and the width of the timeline bar in pixels and the span of it in seconds
+func (s *Model2D) timelineArea() (x, y, barWidth int, span float64) {
+	width, height := s.gl.SceneSize()
+	last := s.last
+	if s.metrics.Time.After(last) {
+		last = s.metrics.Time
+	}
+	return hudMargin, height - hudMargin - timelineHeight, width - hudMargin*2, last.Sub(s.start).Seconds()
+}
+
+// drawPrompt draws the text being typed above the timeline
+func (s *Model2D) drawPrompt() {
+	label, text, ok := s.gl.PromptText()
+	if !ok {
+		return
+	}
+	_, height := s.gl.SceneSize()
+	y := height - hudMargin*4 - timelineHeight - utils.FontHeight
+	s.drawPanel(hudMargin, y, []string{label + ": " + text + "_"})
+}
+
+// groupSummary makes the line of sizes of groups by ID, limited to the first few groups
+func groupSummary(m *Metrics) string {
+	line := "members "
+	for idx, membership := range m.Memberships {
+		if idx == hudGroupLimit {
+			line += " ..."
+			break
+		}
+		line += fmt.Sprintf(" #%d:%d", membership.Group, len(membership.Nids))
+	}
+	return line + fmt.Sprintf(" isolated:%d", m.IsolatedNodes)
+}
+
+// drawPanel draws lines of text on the panel
+func (s *Model2D) drawPanel(x, y int, lines []string) {
+	width := 0
+	for _, line := range lines {
+		if len(line) > width {
+			width = len(line)
+		}
+	}
+	s.gl.SetColor(s.theme.Panel)
+	s.gl.Rect(x, y, width*utils.FontWidth+hudMargin*2, len(lines)*utils.FontHeight+hudMargin*2)
+
+	s.gl.SetColor(s.theme.Text)
+	for idx, line := range lines {
+		s.gl.Text(x+hudMargin, y+hudMargin+idx*utils.FontHeight, line)
+	}
+}
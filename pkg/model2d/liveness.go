@@ -0,0 +1,62 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Liveness decides whether nodes are alive in each frame
+type Liveness int
+
+const (
+	// LivenessVouched keeps a stale node alive while a fresh neighbor has a link to it
+	LivenessVouched Liveness = iota
+	// LivenessStrict disables a node when it has not output logs for the timeout
+	LivenessStrict
+	// LivenessLeave keeps a node alive until it outputs a `leave` log
+	LivenessLeave
+)
+
+var livenessNames = []string{
+	"vouched",
+	"strict",
+	"leave",
+}
+
+// ParseLiveness gets the liveness policy by the name
+func ParseLiveness(name string) (Liveness, error) {
+	for idx, v := range livenessNames {
+		if v == name {
+			return Liveness(idx), nil
+		}
+	}
+	return LivenessVouched, fmt.Errorf("liveness should be one of %s: %s",
+		strings.Join(livenessNames, ", "), name)
+}
+
+func (l Liveness) String() string {
+	return livenessNames[l]
+}
+
+// SetLiveness sets the timeout of nodes and the policy to decide whether nodes are alive
+func (s *Model2D) SetLiveness(timeout time.Duration, liveness Liveness) {
+	s.timeout = timeout
+	s.liveness = liveness
+}
@@ -0,0 +1,163 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"sort"
+	"time"
+)
+
+// Metrics is the summary of the network state for each second
+type Metrics struct {
+	Time time.Time `json:"time"`
+	// Nodes is the count of enabled nodes and KnownNodes includes disabled nodes,
+	// Groups and GroupSizes count only groups having the minimum count of members like Memberships
+	Nodes         int     `json:"nodes"`
+	KnownNodes    int     `json:"knownNodes"`
+	VouchedNodes  int     `json:"vouchedNodes"`
+	Groups        int     `json:"groups"`
+	LargestGroup  int     `json:"largestGroup"`
+	GroupSizes    []int   `json:"groupSizes"`
+	MutualLinks   int     `json:"mutualLinks"`
+	OneWayLinks   int     `json:"oneWayLinks"`
+	AverageDegree float64 `json:"averageDegree"`
+	SeedConnected int     `json:"seedConnected"`
+	Onlyone       int     `json:"onlyone"`
+	// IsolatedNodes is the count of enabled nodes in groups smaller than the minimum size
+	IsolatedNodes int               `json:"isolatedNodes"`
+	Memberships   []GroupMembership `json:"memberships"`
+	// Required2D is the count of required-2D entries of enabled nodes to enabled nodes and
+	// Established2D is the count of them linked in both directions
+	Required2D    int `json:"required2D"`
+	Established2D int `json:"established2D"`
+	// Established2DRate is the percentage of Established2D in Required2D
+	Established2DRate float64 `json:"established2DRate"`
+	// UnknownRecords is the total count of records of messages without handlers and
+	// BadRecords is the total count of records skipped by errors
+	UnknownRecords int `json:"unknownRecords"`
+	BadRecords     int `json:"badRecords"`
+	// DeliveredPackets is the total count of received packets, IncompletePackets is the count of them having
+	// paths cut by missing relay records, PacketHops and ShortestPacketHops are the total hop counts of them
+	// and the shortest paths, packets without the complete path or the shortest path are excluded
+	DeliveredPackets   int `json:"deliveredPackets"`
+	IncompletePackets  int `json:"incompletePackets"`
+	PacketHops         int `json:"packetHops"`
+	ShortestPacketHops int `json:"shortestPacketHops"`
+	// total counts of pub/sub and map operations
+	Publishes     int `json:"publishes"`
+	Deliveries    int `json:"deliveries"`
+	MapOperations int `json:"mapOperations"`
+}
+
+// GroupMembership is the list of enabled nodes in a group
+type GroupMembership struct {
+	Group int      `json:"group"`
+	Nids  []string `json:"nids"`
+}
+
+func (s *Model2D) computeMetrics(current *time.Time) *Metrics {
+	m := &Metrics{
+		Time:               *current,
+		KnownNodes:         len(s.nodes),
+		Groups:             len(s.groupSizes),
+		GroupSizes:         append([]int{}, s.groupSizes...),
+		UnknownRecords:     s.unknownRecords,
+		BadRecords:         s.badRecords,
+		DeliveredPackets:   s.deliveredPackets,
+		IncompletePackets:  s.incompletePackets,
+		PacketHops:         s.packetHops,
+		ShortestPacketHops: s.shortestPacketHops,
+		Publishes:          s.publishes,
+		Deliveries:         s.deliveries,
+		MapOperations:      s.mapOperations,
+	}
+	if len(s.groupSizes) != 0 {
+		m.LargestGroup = s.groupSizes[0]
+	}
+
+	degrees := 0
+	memberships := make(map[int][]string)
+	for _, node := range s.nodes {
+		if !node.enable {
+			continue
+		}
+		m.Nodes++
+		if node.group == 0 {
+			m.IsolatedNodes++
+		} else {
+			memberships[node.group] = append(memberships[node.group], node.nid)
+		}
+		if node.vouched {
+			m.VouchedNodes++
+		}
+		degrees += len(node.links)
+		if node.seedLinkStatus == LinkStatusOnline {
+			m.SeedConnected++
+		}
+		if node.isOnlyone {
+			m.Onlyone++
+		}
+
+		for _, nid := range node.links {
+			pair, ok := s.nodes[nid]
+			if !ok || !pair.enable {
+				continue
+			}
+			if !pair.hasLink(node.nid) {
+				m.OneWayLinks++
+			} else if node.nid < nid {
+				// count each pair once
+				m.MutualLinks++
+			}
+		}
+
+		for _, nid := range node.required2D {
+			// links to unknown or disabled nodes can not be established
+			pair, ok := s.nodes[nid]
+			if !ok || !pair.enable {
+				continue
+			}
+			m.Required2D++
+			if node.hasLink(nid) && pair.hasLink(node.nid) {
+				m.Established2D++
+			}
+		}
+	}
+
+	m.Memberships = make([]GroupMembership, 0, len(memberships))
+	for group, nids := range memberships {
+		sort.Strings(nids)
+		m.Memberships = append(m.Memberships, GroupMembership{
+			Group: group,
+			Nids:  nids,
+		})
+	}
+	sort.Slice(m.Memberships, func(i, j int) bool {
+		return m.Memberships[i].Group < m.Memberships[j].Group
+	})
+
+	if m.Nodes != 0 {
+		m.AverageDegree = float64(degrees) / float64(m.Nodes)
+	}
+	if m.Required2D != 0 {
+		m.Established2DRate = 100.0 * float64(m.Established2D) / float64(m.Required2D)
+	} else {
+		m.Established2DRate = 100.0
+	}
+
+	return m
+}
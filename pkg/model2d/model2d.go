@@ -39,43 +39,119 @@ const (
 )
 
 const (
-	timeout                  = 4 * time.Second
+	defaultTimeout           = 4 * time.Second
+	defaultConvergenceHold   = 10 * time.Second
+	defaultMinGroupSize      = 3
 	messageCurrentPosition   = "current position"
 	messageLinks             = "links"
 	messageRouting1DRequired = "routing 1d required"
 	messageRouting2DRequired = "routing 2d required"
 	messageLinkStatus        = "link status"
+	messageLeave             = "leave"
 )
 
 type Drawer interface {
+	setup(*utils.GL)
 	draw(*utils.GL, map[string]*Node, *time.Time) error
 }
 
 // Model2D is the instance for sphere module
 type Model2D struct {
-	accessor *utils.Accessor
-	drawer   Drawer
-	nodes    map[string]*Node
-	gl       *utils.GL
-	follow   bool
-	tail     bool
+	accessor   *utils.Accessor
+	drawer     Drawer
+	nodes      map[string]*Node
+	gl         *utils.GL
+	theme      *utils.Theme
+	follow     bool
+	tail       bool
+	validator  *Validator
+	hud        bool
+	groupSizes []int
+	metrics    *Metrics
+	// history of metrics for charts
+	chartSeconds int
+	history      []*Metrics
+	convergence  convergence
+	timeout      time.Duration
+	liveness     Liveness
+	connectivity Connectivity
+	minGroupSize int
+	// count of records of messages without handlers
+	unknownMessages map[string]int
+	unknownRecords  int
+	// bad records are skipped and counted unless strict
+	strict     bool
+	badRecords int
+	// traces of packets and statistics of delivered packets
+	packets            map[string]*packet
+	showPackets        bool
+	deliveredPackets   int
+	incompletePackets  int
+	packetHops         int
+	shortestPacketHops int
+	// transient activities of pub/sub and map, and total counts of them
+	activities     []*activity
+	showActivities bool
+	publishes      int
+	deliveries     int
+	mapOperations  int
+	// panel of raw records, the node selected by clicking and whether the replay is paused
+	browser  recordBrowser
+	selected string
+	paused   bool
+	// view is the index of the view of the window to draw into
+	view int
+	// camera following the selected node, and trails of all nodes
+	focus        bool
+	trailSeconds int
+	showTrails   bool
+	// filter of nodes to draw and count of enabled nodes matching it
+	filter        *Filter
+	filterMode    FilterMode
+	filterMatched int
+	// moments marked to compare, snapshots taken when playback reached them
+	// and the difference of the network state between them
+	diffMoments   []Moment
+	diffSnapshots []*snapshot
+	diff          *TopologyDiff
+	// events detected from the beginning
+	detector    *eventDetector
+	eventWriter EventWriter
+	events      []*Event
+	// time range of the source data, start is later than the earliest time with tail
+	earliest time.Time
+	start    time.Time
+	last     time.Time
 }
 
 // Node contains last information for each time
 type Node struct {
 	enable         bool
+	vouched        bool
+	left           bool
 	group          int
+	component      int
 	nid            string
 	x              float64
 	y              float64
 	links          []string
 	required1D     []string
 	required2D     []string
+	missing2D      []string
+	extra2D        []string
 	timestamp      time.Time
+	firstSeen      time.Time
 	seedLinkStatus int
 	nodeLinkStatus int
 	authStatus     int
 	isOnlyone      bool
+	flash          time.Time
+	attributes     map[string]interface{}
+	packetHops     []packetHop
+	activities     []*activity
+	statusHistory  []statusChange
+	positions      []trailPoint
+	filteredOut    bool
 }
 
 // ParameterCurrentPosition is for decoding parameter of `current position` log
@@ -113,38 +189,44 @@ func init() {
 }
 
 // NewInstance makes a new instance of Sphere
-func NewInstance(accessor *utils.Accessor, drawer Drawer, gl *utils.GL, follow, tail bool) *Model2D {
-	return &Model2D{
+func NewInstance(accessor *utils.Accessor, drawer Drawer, gl *utils.GL, theme *utils.Theme, follow, tail bool) *Model2D {
+	s := &Model2D{
 		accessor: accessor,
 		drawer:   drawer,
 		nodes:    make(map[string]*Node),
 		gl:       gl,
+		theme:    theme,
 		follow:   follow,
 		tail:     tail,
+		hud:      true,
+		convergence: convergence{
+			hold: defaultConvergenceHold,
+		},
+		detector:        newEventDetector(),
+		timeout:         defaultTimeout,
+		minGroupSize:    defaultMinGroupSize,
+		unknownMessages: make(map[string]int),
+		packets:         make(map[string]*packet),
+		showPackets:     true,
+		showActivities:  true,
 	}
+	accessor.SetErrorHandler(s.handleRecordError)
+	return s
 }
 
-// Run is an entory point for sphere module
-func (s *Model2D) Run() error {
-	// get time range from mongodb
-	current, err := s.accessor.GetEarliestTime()
-	if err != nil {
-		return err
-	}
-	if current == nil {
-		log.Fatalln("nothing data")
-	}
+// SetValidator sets the validator checking required-2D of nodes in each frame
+func (s *Model2D) SetValidator(validator *Validator) {
+	s.validator = validator
+}
 
-	// tail option
-	if s.tail {
-		current, err = s.accessor.GetLastTime()
-		if err != nil {
-			return err
-		}
-		*current = current.Add(-10 * time.Second)
-	}
+// SetHUD sets whether to show metrics of each frame
+func (s *Model2D) SetHUD(enable bool) {
+	s.hud = enable
+}
 
-	last, err := s.accessor.GetLastTime()
+// Run is an entory point for sphere module
+func (s *Model2D) Run() error {
+	current, last, err := s.getTimeRange()
 	if err != nil {
 		return err
 	}
@@ -152,10 +234,15 @@ func (s *Model2D) Run() error {
 	if err = s.updateByLogs(current); err != nil {
 		return err
 	}
+	s.keepSnapshots(current)
 
 	// setup opengl
+	if s.chartSeconds > 0 {
+		s.gl.SetPanelWidth(chartPanelWidth)
+	}
 	s.gl.Setup()
 	defer s.gl.Quit()
+	s.setupView()
 
 	if s.follow {
 		s.gl.SetImageDigit(6)
@@ -165,211 +252,328 @@ func (s *Model2D) Run() error {
 
 	// main loop until closing the window or existing data
 	for s.gl.Loop() {
-		*current = current.Add(time.Second)
+		// keep drawing the current frame while paused without saving it as an image
+		s.gl.SetSaveFrame(!s.paused)
+		if !s.paused {
+			*current = current.Add(time.Second)
+
+			if s.follow {
+				if current.UnixNano() > time.Now().Add(-5*time.Second).UnixNano() {
+					time.Sleep(1 * time.Second)
+				}
 
-		if s.follow {
-			if current.UnixNano() > time.Now().Add(-5*time.Second).UnixNano() {
-				time.Sleep(1 * time.Second)
+			} else {
+				if current.UnixNano() > last.UnixNano() {
+					break
+				}
 			}
 
-		} else {
-			if current.UnixNano() > last.UnixNano() {
-				break
+			// update data
+			if err = s.step(current); err != nil {
+				return err
 			}
 		}
 
-		// update data
-		if err = s.updateByLogs(current); err != nil {
+		if err = s.drawFrame(current); err != nil {
 			return err
 		}
-		s.disableTimeoutNode(current)
-		s.setGroupNumber()
 
-		// draw data
-		if err = s.drawer.draw(s.gl, s.nodes, current); err != nil {
-			return err
+		if s.convergence.shouldStop() {
+			break
 		}
 	}
 
+	return s.convergence.result()
+}
+
+// setupView sets up the drawer and keys after setting up opengl
+func (s *Model2D) setupView() {
+	s.drawer.setup(s.gl)
+	s.setupKeys()
+}
+
+// drawFrame draws the model and overlays of the current time
+func (s *Model2D) drawFrame(current *time.Time) error {
+	nodes := s.applyFilter(current)
+	s.updateCamera(nodes)
+	if err := s.drawer.draw(s.gl, nodes, current); err != nil {
+		return err
+	}
+	if s.showPackets {
+		s.drawPackets(nodes)
+	}
+	if s.showActivities {
+		s.drawActivities(nodes, current)
+	}
+	if s.showTrails {
+		s.drawTrails(nodes, current)
+	}
+	s.drawDiff()
+	s.drawFocus(nodes, current)
+	s.drawSelection()
+	if s.hud {
+		s.drawHUD()
+	}
+	if s.chartSeconds > 0 {
+		s.drawCharts()
+	}
+	if s.browser.show {
+		if err := s.drawRecords(current); err != nil {
+			return err
+		}
+	}
+	s.drawPrompt()
 	return nil
 }
 
-func (s *Model2D) updateByLogs(current *time.Time) error {
-	records, err := s.accessor.GetByTime(current)
+// Replay updates the model for each second of the source data without drawing,
+// the handler is called with metrics of each second
+func (s *Model2D) Replay(handler func(*Metrics) error) error {
+	current, last, err := s.getTimeRange()
 	if err != nil {
 		return err
 	}
 
-	for _, record := range records {
-		switch record.Message {
-		case messageCurrentPosition:
-			var p ParameterCurrentPosition
-			if err = bson.Unmarshal(record.Param, &p); err != nil {
-				return err
-			}
-			node := s.getNode(&record)
-			node.x = p.Coordinate.X
-			node.y = p.Coordinate.Y
-
-		case messageLinks:
-			var p ParameterLinks
-			if err = bson.Unmarshal(record.Param, &p); err != nil {
-				return err
-			}
-			node := s.getNode(&record)
-			node.links = p.Nids
+	if err = s.updateByLogs(current); err != nil {
+		return err
+	}
 
-		case messageRouting1DRequired:
-			var p ParameterLinks
-			if err = bson.Unmarshal(record.Param, &p); err != nil {
-				return err
-			}
-			node := s.getNode(&record)
-			node.required1D = p.Nids
+	for {
+		*current = current.Add(time.Second)
+		if current.UnixNano() > last.UnixNano() {
+			break
+		}
 
-		case messageRouting2DRequired:
-			var p ParameterRouting2DRequired
-			if err = bson.Unmarshal(record.Param, &p); err != nil {
-				return err
-			}
-			node := s.getNode(&record)
-			node.required2D = make([]string, len(p.Nids))
-			idx := 0
-			for k := range p.Nids {
-				node.required2D[idx] = k
-				idx++
-			}
+		if err = s.step(current); err != nil {
+			return err
+		}
+		if err = handler(s.metrics); err != nil {
+			return err
+		}
 
-		case messageLinkStatus:
-			var p ParameterLinkStatus
-			if err = bson.Unmarshal(record.Param, &p); err != nil {
-				return err
-			}
-			node := s.getNode(&record)
-			node.seedLinkStatus = p.Seed
-			node.nodeLinkStatus = p.Node
-			node.authStatus = p.Auth
-			node.isOnlyone = p.Onlyone
+		if s.convergence.shouldStop() {
+			break
 		}
 	}
 
-	return nil
+	return s.convergence.result()
 }
 
-func (s *Model2D) disableTimeoutNode(current *time.Time) {
-	for nid, node := range s.nodes {
-		if node.timestamp.Add(timeout).After(*current) {
-			node.enable = true
-			continue
-		}
+// getTimeRange gets the time to start and the time of the last record
+func (s *Model2D) getTimeRange() (*time.Time, *time.Time, error) {
+	// get time range from mongodb
+	current, err := s.accessor.GetEarliestTime()
+	if err != nil {
+		return nil, nil, err
+	}
+	if current == nil {
+		log.Fatalln("nothing data")
+	}
+	s.earliest = *current
 
-		node.enable = false
-		for _, nextNid := range node.links {
-			if next, ok := s.nodes[nextNid]; ok {
-				if next.timestamp.Add(timeout).After(*current) && next.hasLink(nid) {
-					node.enable = true
-					break
-				}
-			}
+	// tail option
+	if s.tail {
+		current, err = s.accessor.GetLastTime()
+		if err != nil {
+			return nil, nil, err
 		}
+		*current = current.Add(-10 * time.Second)
 	}
-}
-
-type group struct {
-	count  int
-	assign int
-}
 
-type groups []*group
+	last, err := s.accessor.GetLastTime()
+	if err != nil {
+		return nil, nil, err
+	}
 
-func (g groups) Len() int {
-	return len(g)
+	s.start = *current
+	s.last = *last
+	return current, last, nil
 }
 
-func (g groups) Swap(i, j int) {
-	g[i], g[j] = g[j], g[i]
+// step updates the model by the records of the current time
+func (s *Model2D) step(current *time.Time) error {
+	if err := s.updateByLogs(current); err != nil {
+		return err
+	}
+	s.disableTimeoutNode(current)
+	for _, node := range s.nodes {
+		node.recordStatus(*current)
+	}
+	s.collectPackets(current)
+	s.collectActivities(current)
+	s.recordPositions(current)
+	s.setGroupNumber()
+	if err := s.detectEvents(current); err != nil {
+		return err
+	}
+	if s.validator != nil {
+		s.validator.validate(s.nodes)
+	}
+	s.metrics = s.computeMetrics(current)
+	s.convergence.check(s.metrics)
+	s.recordHistory()
+	s.keepSnapshot(current)
+	return nil
 }
 
-func (g groups) Less(i, j int) bool {
-	return g[i].count > g[j].count
+func (s *Model2D) setupKeys() {
+	s.gl.OnKey('a', func() {
+		s.showActivities = !s.showActivities
+	})
+	s.gl.OnKey('h', func() {
+		s.hud = !s.hud
+	})
+	s.gl.OnKey('p', func() {
+		s.showPackets = !s.showPackets
+	})
+	if s.validator != nil {
+		s.gl.OnKey('v', func() {
+			s.validator.toggle(s.nodes)
+		})
+	}
+	s.setupRecordKeys()
+	s.setupFilterKeys()
+	s.setupFocusKeys()
+	s.setupTrailKeys()
+	s.setupDiffKeys()
 }
 
-func (s *Model2D) setGroupNumber() {
-	for _, node := range s.nodes {
-		node.group = 0
+func (s *Model2D) updateByLogs(current *time.Time) error {
+	records, err := s.accessor.GetByTime(current)
+	if err != nil {
+		return err
 	}
+	s.browser.keepRecords(records)
 
-	// assign temp ID
-	groupMap := make(map[int]*group)
-	for _, node := range s.nodes {
-		if node.group == 0 {
-			idx := len(groupMap) + 1
-			groupMap[idx] = &group{
-				count: s.findGroup(idx, node),
+	for _, record := range records {
+		handler, ok := getHandler(record.Message)
+		if !ok {
+			s.countUnknownMessage(record.Message)
+			continue
+		}
+
+		var param interface{}
+		if handler.NewParam != nil {
+			param = handler.NewParam()
+			if err = bson.Unmarshal(record.Param, param); err != nil {
+				if err = s.handleRecordError(utils.NewRecordError(&record, err)); err != nil {
+					return err
+				}
+				continue
 			}
 		}
+		handler.Apply(s.getNode(&record), param)
 	}
 
-	// order by member count
-	groupOrder := make(groups, 0)
-	for _, v := range groupMap {
-		groupOrder = append(groupOrder, v)
-	}
-	sort.Sort(groupOrder)
-	for idx, v := range groupOrder {
-		v.assign = idx + 1
+	return nil
+}
+
+// SetStrict sets whether to stop by bad records instead of skipping them
+func (s *Model2D) SetStrict(strict bool) {
+	s.strict = strict
+}
+
+// handleRecordError skips and counts the bad record, or returns the error in strict mode
+func (s *Model2D) handleRecordError(err *utils.RecordError) error {
+	if s.strict {
+		return err
 	}
+	log.Printf("skip the bad record: %v", err)
+	s.badRecords++
+	return nil
+}
 
-	// assign ID 0 for very small group
-	for _, g := range groupOrder {
-		if g.count < 3 {
-			g.assign = 0
-		}
+// countUnknownMessage counts records of the message without handlers, it is logged once for each message
+func (s *Model2D) countUnknownMessage(message string) {
+	if s.unknownMessages[message] == 0 {
+		log.Printf("no handler for the message: %s", message)
 	}
+	s.unknownMessages[message]++
+	s.unknownRecords++
+}
 
-	// assign new ID
-	for _, node := range s.nodes {
-		node.group = groupMap[node.group].assign
+// UnknownMessages gets the count of records for each message without handlers
+func (s *Model2D) UnknownMessages() map[string]int {
+	counts := make(map[string]int, len(s.unknownMessages))
+	for message, count := range s.unknownMessages {
+		counts[message] = count
 	}
+	return counts
 }
 
-func (s *Model2D) findGroup(group int, node *Node) int {
-	node.group = group
-	count := 1
-	for _, linkNid := range node.links {
-		if link, ok := s.nodes[linkNid]; ok {
-			if link.enable && link.group == 0 {
-				count += s.findGroup(group, link)
+func (s *Model2D) disableTimeoutNode(current *time.Time) {
+	for nid, node := range s.nodes {
+		node.vouched = false
+		if s.liveness == LivenessLeave {
+			node.enable = !node.left
+			continue
+		}
+
+		if node.timestamp.Add(s.timeout).After(*current) {
+			node.enable = true
+			continue
+		}
+
+		node.enable = false
+		if s.liveness == LivenessStrict {
+			continue
+		}
+		for _, nextNid := range node.links {
+			if next, ok := s.nodes[nextNid]; ok {
+				if next.timestamp.Add(s.timeout).After(*current) && next.hasLink(nid) {
+					node.enable = true
+					node.vouched = true
+					break
+				}
 			}
 		}
 	}
-	return count
 }
 
 func (s *Model2D) getNode(record *utils.Record) *Node {
 	nid := record.NID
 	if _, ok := s.nodes[nid]; !ok {
 		s.nodes[nid] = &Node{
-			enable: true,
-			nid:    nid,
+			enable:    true,
+			nid:       nid,
+			firstSeen: record.TimeNtv,
 		}
 	}
 	node := s.nodes[nid]
 	node.timestamp = record.TimeNtv
+	// the node joined again if it outputs logs after leaving
+	node.left = false
 	return node
 }
 
-func (n *Node) hasLink(nid string) bool {
-	for _, v := range n.links {
-		if v == nid {
-			return true
+// enabledNodes makes the list of enabled nodes ordered by nid
+func enabledNodes(nodes map[string]*Node) []*Node {
+	enabled := make([]*Node, 0)
+	for _, node := range nodes {
+		if node.enable {
+			enabled = append(enabled, node)
 		}
 	}
-	return false
+	sort.Slice(enabled, func(i, j int) bool {
+		return enabled[i].nid < enabled[j].nid
+	})
+	return enabled
+}
+
+func (n *Node) hasLink(nid string) bool {
+	return contains(n.links, nid)
+}
+
+func (n *Node) hasRequired1D(nid string) bool {
+	return contains(n.required1D, nid)
 }
 
 func (n *Node) hasRequired2D(nid string) bool {
-	for _, v := range n.required2D {
+	return contains(n.required2D, nid)
+}
+
+func contains(nids []string, nid string) bool {
+	for _, v := range nids {
 		if v == nid {
 			return true
 		}
@@ -0,0 +1,283 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"sort"
+	"time"
+
+	"github.com/llamerada-jp/colonio-simulator-view/pkg/utils"
+)
+
+const (
+	messagePacketSend    = "packet send"
+	messagePacketRelay   = "packet relay"
+	messagePacketReceive = "packet receive"
+
+	// packetLifetime is the time to keep drawing a packet after the last record of it
+	packetLifetime = 3 * time.Second
+)
+
+// ParameterPacket is for decoding parameter of `packet send`, `packet relay` and `packet receive` logs
+type ParameterPacket struct {
+	ID  string `bson:"id"`
+	Src string `bson:"src"`
+	Dst string `bson:"dst"`
+	// Hop is the next node the packet is forwarded to, it is empty for `packet receive`
+	Hop string `bson:"hop"`
+}
+
+// packetHop is a record of a packet observed at the node, it is kept in the node until the model collects it
+type packetHop struct {
+	ParameterPacket
+	received bool
+}
+
+// packet is the trace of a packet through nodes
+type packet struct {
+	id  string
+	src string
+	dst string
+	// next is the node forwarded to from each node
+	next     map[string]string
+	path     []string
+	received bool
+	// receiver is the node which received the packet
+	receiver string
+	updated  time.Time
+	// shortest is the hop count of the shortest path by links established in both directions when it was received,
+	// -1 means no path
+	shortest int
+}
+
+// packetDrawer is implemented by drawers which can draw traces of packets
+type packetDrawer interface {
+	drawPackets(gl *utils.GL, nodes map[string]*Node, packets []*packet)
+}
+
+func init() {
+	for _, message := range []string{messagePacketSend, messagePacketRelay, messagePacketReceive} {
+		received := message == messagePacketReceive
+		RegisterHandler(message, MessageHandler{
+			NewParam: func() interface{} {
+				return &ParameterPacket{}
+			},
+			Apply: func(node *Node, param interface{}) {
+				node.packetHops = append(node.packetHops, packetHop{
+					ParameterPacket: *param.(*ParameterPacket),
+					received:        received,
+				})
+			},
+		})
+	}
+}
+
+// hops gets the hop count of the path from the source
+func (p *packet) hops() int {
+	return len(p.path) - 1
+}
+
+// updatePath follows forwarded nodes from the source
+func (p *packet) updatePath() {
+	p.path = []string{p.src}
+	visited := map[string]bool{p.src: true}
+	current := p.src
+	for {
+		next, ok := p.next[current]
+		if !ok || len(next) == 0 || visited[next] {
+			break
+		}
+		p.path = append(p.path, next)
+		visited[next] = true
+		current = next
+	}
+}
+
+// collectPackets moves packet records kept in nodes into traces of packets,
+// receives are applied after sends and relays of the same second and ones of unknown or expired packets are ignored
+func (s *Model2D) collectPackets(current *time.Time) {
+	// receivers are nids of nodes received packets by the id
+	receivers := make(map[string][]string)
+	for nid, node := range s.nodes {
+		for _, hop := range node.packetHops {
+			if hop.received {
+				receivers[hop.ID] = append(receivers[hop.ID], nid)
+				continue
+			}
+			p, ok := s.packets[hop.ID]
+			if !ok {
+				p = &packet{
+					id:   hop.ID,
+					src:  hop.Src,
+					dst:  hop.Dst,
+					next: make(map[string]string),
+				}
+				s.packets[hop.ID] = p
+			}
+			p.updated = *current
+			if len(hop.Hop) != 0 {
+				p.next[nid] = hop.Hop
+			}
+		}
+		node.packetHops = nil
+	}
+
+	received := make([]*packet, 0)
+	for id, nids := range receivers {
+		p, ok := s.packets[id]
+		if !ok || current.Sub(p.updated) > packetLifetime {
+			continue
+		}
+		p.updated = *current
+		if !p.received {
+			sort.Strings(nids)
+			p.received = true
+			p.receiver = nids[0]
+			received = append(received, p)
+		}
+	}
+
+	for id, p := range s.packets {
+		p.updatePath()
+		if current.Sub(p.updated) > packetLifetime {
+			delete(s.packets, id)
+		}
+	}
+	sort.Slice(received, func(i, j int) bool {
+		return received[i].id < received[j].id
+	})
+	for _, p := range received {
+		s.reportPacket(p)
+	}
+}
+
+// complete returns true if the path reaches the receiver, the path is cut at the node without the relay record
+func (p *packet) complete() bool {
+	return p.path[len(p.path)-1] == p.receiver
+}
+
+// reportPacket compares the hop count with the shortest path when the packet is received
+func (s *Model2D) reportPacket(p *packet) {
+	p.shortest = s.shortestHops(p.src, p.receiver)
+	s.deliveredPackets++
+	if !p.complete() {
+		s.incompletePackets++
+		return
+	}
+	// compare only packets having the shortest path
+	if p.shortest >= 0 {
+		s.packetHops += p.hops()
+		s.shortestPacketHops += p.shortest
+	}
+}
+
+// shortestHops finds the hop count of the shortest path by BFS on links established in both directions
+func (s *Model2D) shortestHops(src, dst string) int {
+	if src == dst {
+		return 0
+	}
+	distances := map[string]int{src: 0}
+	queue := []string{src}
+	for len(queue) != 0 {
+		nid := queue[0]
+		queue = queue[1:]
+		node, ok := s.nodes[nid]
+		if !ok || !node.enable {
+			continue
+		}
+		for _, pairNid := range node.links {
+			if _, ok := distances[pairNid]; ok {
+				continue
+			}
+			pair, ok := s.nodes[pairNid]
+			if !ok || !pair.enable || !pair.hasLink(nid) {
+				continue
+			}
+			distances[pairNid] = distances[nid] + 1
+			if pairNid == dst {
+				return distances[pairNid]
+			}
+			queue = append(queue, pairNid)
+		}
+	}
+	return -1
+}
+
+// activePackets gets packets to be drawn ordered by id
+func (s *Model2D) activePackets() []*packet {
+	packets := make([]*packet, 0, len(s.packets))
+	for _, p := range s.packets {
+		packets = append(packets, p)
+	}
+	sort.Slice(packets, func(i, j int) bool {
+		return packets[i].id < packets[j].id
+	})
+	return packets
+}
+
+func (s *Model2D) drawPackets(nodes map[string]*Node) {
+	if drawer, ok := s.drawer.(packetDrawer); ok && len(s.packets) != 0 {
+		drawer.drawPackets(s.gl, nodes, s.activePackets())
+	}
+}
+
+func (s *Plane) drawPackets(gl *utils.GL, nodes map[string]*Node, packets []*packet) {
+	for _, p := range packets {
+		var prev *Node
+		for _, nid := range p.path {
+			node, ok := nodes[nid]
+			if !ok {
+				break
+			}
+			if prev != nil {
+				gl.SetColor(s.theme.Packet)
+				s.line(gl, prev, node, -0.8, -0.8)
+			}
+			prev = node
+		}
+		// the packet is at the last node of the path
+		if prev != nil {
+			x, y := s.convertCoordinate(prev.x, prev.y)
+			gl.SetColor(s.theme.Packet)
+			gl.Box3(x, y, -1.0, 8.0)
+		}
+	}
+}
+
+func (s *Sphere) drawPackets(gl *utils.GL, nodes map[string]*Node, packets []*packet) {
+	for _, p := range packets {
+		var prev *Node
+		for _, nid := range p.path {
+			node, ok := nodes[nid]
+			if !ok {
+				break
+			}
+			if prev != nil {
+				x1, y1, z1 := s.convertCoordinate(prev.x, prev.y)
+				x2, y2, z2 := s.convertCoordinate(node.x, node.y)
+				gl.SetColor(s.reduceColorByZ(s.theme.Packet, (z1+z2)/2.0))
+				gl.Line3(x1, y1, sphereFront(z1), x2, y2, sphereFront(z2))
+			}
+			prev = node
+		}
+		if prev != nil {
+			x, y, z := s.convertCoordinate(prev.x, prev.y)
+			gl.SetColor(s.reduceColorByZ(s.theme.Packet, z))
+			gl.Box3(x, y, z, 8.0)
+		}
+	}
+}
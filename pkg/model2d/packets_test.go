@@ -0,0 +1,110 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCollectPackets(t *testing.T) {
+	type record struct {
+		second int
+		nid    string
+		hop    string
+		// received is true for `packet receive`
+		received bool
+	}
+	tests := []struct {
+		name       string
+		records    []record
+		delivered  int
+		incomplete int
+		hops       int
+		shortest   int
+	}{
+		{
+			name: "delivered",
+			records: []record{
+				{0, "a", "b", false},
+				{0, "b", "c", false},
+				{1, "c", "", true},
+			},
+			delivered: 1, hops: 2, shortest: 1,
+		},
+		{
+			name: "received in the same second",
+			records: []record{
+				{0, "c", "", true},
+				{0, "a", "c", false},
+			},
+			delivered: 1, hops: 1, shortest: 1,
+		},
+		{
+			name: "received without send",
+			records: []record{
+				{0, "c", "", true},
+			},
+		},
+		{
+			name: "received after expiry",
+			records: []record{
+				{0, "a", "b", false},
+				{5, "c", "", true},
+			},
+		},
+		{
+			name: "missing relay",
+			records: []record{
+				{0, "a", "b", false},
+				{1, "c", "", true},
+			},
+			delivered: 1, incomplete: 1,
+		},
+	}
+	for _, tt := range tests {
+		s := &Model2D{
+			nodes: map[string]*Node{
+				"a": {nid: "a", enable: true, links: []string{"b", "c"}},
+				"b": {nid: "b", enable: true, links: []string{"a", "c"}},
+				"c": {nid: "c", enable: true, links: []string{"a", "b"}},
+			},
+			packets: make(map[string]*packet),
+		}
+		start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		for second := 0; second <= 6; second++ {
+			for _, r := range tt.records {
+				if r.second != second {
+					continue
+				}
+				node := s.nodes[r.nid]
+				node.packetHops = append(node.packetHops, packetHop{
+					ParameterPacket: ParameterPacket{ID: "p", Src: "a", Dst: "c", Hop: r.hop},
+					received:        r.received,
+				})
+			}
+			current := start.Add(time.Duration(second) * time.Second)
+			s.collectPackets(&current)
+		}
+		if s.deliveredPackets != tt.delivered || s.incompletePackets != tt.incomplete ||
+			s.packetHops != tt.hops || s.shortestPacketHops != tt.shortest {
+			t.Errorf("%s: delivered %d incomplete %d hops %d shortest %d, want %d %d %d %d", tt.name,
+				s.deliveredPackets, s.incompletePackets, s.packetHops, s.shortestPacketHops,
+				tt.delivered, tt.incomplete, tt.hops, tt.shortest)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/llamerada-jp/colonio-simulator-view/pkg/utils"
+)
+
+// ColoringMode decides which attribute of nodes is represented by the color
+type ColoringMode int
+
+const (
+	ColorByGroup ColoringMode = iota
+	ColorByAuthStatus
+	ColorByNodeLinkStatus
+	ColorBySeedLinkStatus
+	ColorByDegree
+	ColorByAge
+	ColorByStaleness
+	ColorByNID
+	coloringModeCount
+)
+
+var coloringModeNames = []string{
+	"group",
+	"auth",
+	"node-link",
+	"seed-link",
+	"degree",
+	"age",
+	"staleness",
+	"nid",
+}
+
+// ParseColoringMode gets the coloring mode by the name
+func ParseColoringMode(name string) (ColoringMode, error) {
+	for idx, v := range coloringModeNames {
+		if v == name {
+			return ColoringMode(idx), nil
+		}
+	}
+	return ColorByGroup, fmt.Errorf("coloring mode should be one of %s: %s",
+		strings.Join(coloringModeNames, ", "), name)
+}
+
+func (m ColoringMode) String() string {
+	return coloringModeNames[m]
+}
+
+func (m ColoringMode) isNumeric() bool {
+	return m == ColorByDegree || m == ColorByAge || m == ColorByStaleness
+}
+
+const (
+	// DetailRequired2D draws links required by 2D routing, one-way ones are drawn by the color of one-way links
+	DetailRequired2D uint = iota
+	// DetailOneWay draws one-way links not required by 2D routing in addition
+	DetailOneWay
+	// DetailAllLinks draws links not required by 2D routing in addition
+	DetailAllLinks
+	// DetailRing1D draws links required by 1D routing in addition
+	DetailRing1D
+	detailLevelCount
+)
+
+type linkKind int
+
+const (
+	linkRequired linkKind = iota
+	linkRequiredOneWay
+	linkOneWay
+	linkOther
+)
+
+// painter decides colors of nodes and links, it is shared by drawers
+type painter struct {
+	theme       *utils.Theme
+	coloring    ColoringMode
+	detailLevel uint
+	voronoi     bool
+	current     time.Time
+	// range of numeric attribute of enabled nodes in the current frame
+	minValue float64
+	maxValue float64
+}
+
+func newPainter(detailLevel uint, theme *utils.Theme, coloring ColoringMode) painter {
+	if detailLevel >= detailLevelCount {
+		detailLevel = detailLevelCount - 1
+	}
+	return painter{
+		theme:       theme,
+		coloring:    coloring,
+		detailLevel: detailLevel,
+	}
+}
+
+// SetVoronoi sets whether to shade Voronoi cells of nodes
+func (p *painter) SetVoronoi(enable bool) {
+	p.voronoi = enable
+}
+
+func (p *painter) toggleVoronoi() {
+	p.voronoi = !p.voronoi
+	log.Printf("voronoi: %t", p.voronoi)
+}
+
+// setupKeys binds the keys to cycle coloring modes and detail levels
+func (p *painter) setupKeys(gl *utils.GL) {
+	gl.OnKey('c', func() {
+		p.coloring = (p.coloring + 1) % coloringModeCount
+		log.Printf("coloring: %s", p.coloring)
+	})
+	gl.OnKey('l', func() {
+		p.detailLevel = (p.detailLevel + 1) % detailLevelCount
+		log.Printf("detail level: %d", p.detailLevel)
+	})
+}
+
+// prepare should be called before getting colors in each frame
+func (p *painter) prepare(nodes map[string]*Node, current *time.Time) {
+	p.current = *current
+	if !p.coloring.isNumeric() {
+		return
+	}
+
+	p.minValue = math.Inf(1)
+	p.maxValue = math.Inf(-1)
+	for _, node := range nodes {
+		if !node.enable {
+			continue
+		}
+		value := p.numericValue(node)
+		p.minValue = math.Min(p.minValue, value)
+		p.maxValue = math.Max(p.maxValue, value)
+	}
+}
+
+func (p *painter) nodeColor(node *Node) utils.Color {
+	if isFlashing(node, p.current) {
+		return p.markColor(node, p.theme.Event)
+	}
+	// stale nodes kept alive by neighbors are drawn pale
+	if node.vouched {
+		return p.markColor(node, p.theme.Background.Mix(p.attributeColor(node), 0.4))
+	}
+	return p.markColor(node, p.attributeColor(node))
+}
+
+// markColor gets the color of marks and links of the node, it is dimmed if the node does not match the filter
+func (p *painter) markColor(node *Node, c utils.Color) utils.Color {
+	if node.filteredOut {
+		return p.theme.Background.Mix(c, filterDimRate)
+	}
+	return c
+}
+
+// attributeColor gets the color representing the attribute selected by the coloring mode
+func (p *painter) attributeColor(node *Node) utils.Color {
+	switch p.coloring {
+	case ColorByAuthStatus:
+		return statusColor(p.theme.AuthStatus, node.authStatus)
+
+	case ColorByNodeLinkStatus:
+		return statusColor(p.theme.LinkStatus, node.nodeLinkStatus)
+
+	case ColorBySeedLinkStatus:
+		return statusColor(p.theme.LinkStatus, node.seedLinkStatus)
+
+	case ColorByDegree, ColorByAge, ColorByStaleness:
+		if p.maxValue <= p.minValue {
+			return p.theme.MapColor(0.0)
+		}
+		return p.theme.MapColor((p.numericValue(node) - p.minValue) / (p.maxValue - p.minValue))
+
+	case ColorByNID:
+		hash := fnv.New32a()
+		hash.Write([]byte(node.nid))
+		return p.theme.HashColor(hash.Sum32())
+
+	default:
+		// group IDs start from 1, 0 means the node is in a group smaller than the minimum size
+		if node.group == 0 {
+			return p.theme.Isolated
+		}
+		return p.theme.GroupColor(node.group - 1)
+	}
+}
+
+// cellColor gets the pale color of the node for shading the Voronoi cell
+func (p *painter) cellColor(node *Node) utils.Color {
+	return p.theme.Background.Mix(p.nodeColor(node), 0.35)
+}
+
+// linkKind classifies the link from the node to the pair
+func (p *painter) linkKind(node, pair *Node) linkKind {
+	if node.hasRequired2D(pair.nid) {
+		if !pair.hasLink(node.nid) {
+			return linkRequiredOneWay
+		}
+		return linkRequired
+	}
+	if !pair.hasLink(node.nid) {
+		return linkOneWay
+	}
+	return linkOther
+}
+
+// isVisible returns true if the kind of links should be drawn in the current detail level
+func (p *painter) isVisible(kind linkKind) bool {
+	switch kind {
+	case linkOneWay:
+		return p.detailLevel >= DetailOneWay
+	case linkOther:
+		return p.detailLevel >= DetailAllLinks
+	}
+	return true
+}
+
+// linkColor gets the color of the kind of links from the node having nodeColor
+func (p *painter) linkColor(node *Node, kind linkKind, nodeColor utils.Color) utils.Color {
+	switch kind {
+	case linkRequiredOneWay, linkOneWay:
+		return p.markColor(node, p.theme.OneWayLink)
+	case linkOther:
+		return p.markColor(node, p.theme.Link)
+	}
+	return nodeColor
+}
+
+// isIsolated returns true if the node is in a group smaller than the minimum size
+func (p *painter) isIsolated(node *Node) bool {
+	return node.group == 0
+}
+
+func (p *painter) isRing1DVisible() bool {
+	return p.detailLevel >= DetailRing1D
+}
+
+func (p *painter) numericValue(node *Node) float64 {
+	switch p.coloring {
+	case ColorByDegree:
+		return float64(len(node.links))
+	case ColorByAge:
+		return p.current.Sub(node.firstSeen).Seconds()
+	case ColorByStaleness:
+		return p.current.Sub(node.timestamp).Seconds()
+	}
+	return 0.0
+}
+
+func statusColor(colors []utils.Color, status int) utils.Color {
+	if status < 0 || status >= len(colors) {
+		return colors[0]
+	}
+	return colors[status]
+}
@@ -6,53 +6,134 @@ import (
 	"github.com/llamerada-jp/colonio-simulator-view/pkg/utils"
 )
 
-type Plane struct{}
+// Plane is a drawer instance for plane coordinate system
+type Plane struct {
+	painter
+	// camera is the position drawn at the center of the view
+	camera point2
+}
 
-var colorMap = [][]float32{
-	{0.8, 0.0, 0.8},
-	{0.0, 0.2, 1.0},
-	{0.0, 0.8, 0.2},
-	{1.0, 0.6, 0.0},
+// NewPlaneDrawer make plane drawer instance
+func NewPlaneDrawer(detailLevel uint, theme *utils.Theme, coloring ColoringMode) *Plane {
+	return &Plane{
+		painter: newPainter(detailLevel, theme, coloring),
+	}
+}
+
+func (s *Plane) setup(gl *utils.GL) {
+	s.setupKeys(gl)
+	gl.OnKey('o', s.toggleVoronoi)
 }
 
 func (s *Plane) draw(gl *utils.GL, nodes map[string]*Node, current *time.Time) error {
+	s.prepare(nodes, current)
+
+	if s.voronoi {
+		s.drawVoronoi(gl, nodes)
+	}
+
 	for _, node := range nodes {
 		if !node.enable {
 			continue
 		}
-		colorIdx := node.group
-		if colorIdx >= len(colorMap) {
-			colorIdx = 0
-		}
-		gl.SetRGB(colorMap[colorIdx][0], colorMap[colorIdx][1], colorMap[colorIdx][2])
-		gl.Point3(node.x, node.y, -1.0)
+		nodeColor := s.nodeColor(node)
+		x, y := s.convertCoordinate(node.x, node.y)
+		gl.SetColor(nodeColor)
+		gl.Point3(x, y, -1.0)
 
 		if node.seedLinkStatus == LinkStatusOnline {
-			gl.SetRGB(1.0, 0.0, 0.0)
-			gl.Box3(node.x, node.y, -1.0, 6.0)
+			gl.SetColor(s.markColor(node, s.theme.Seed))
+			gl.Box3(x, y, -1.0, 6.0)
 		}
 		if node.isOnlyone {
-			gl.SetRGB(1.0, 0.0, 0.0)
-			gl.Box3(node.x, node.y, -1.0, 10.0)
+			gl.SetColor(s.markColor(node, s.theme.Onlyone))
+			gl.Box3(x, y, -1.0, 10.0)
+		}
+		if s.isIsolated(node) {
+			gl.SetColor(s.markColor(node, s.theme.Isolated))
+			gl.Box3(x, y, -1.0, 14.0)
 		}
 
 		for _, link := range node.links {
 			if pair, ok := nodes[link]; ok {
+				kind := s.linkKind(node, pair)
+				if !s.isVisible(kind) {
+					continue
+				}
 				z := 0.0
-				if pair.hasLink(node.nid) {
-					if node.hasRequired2D(pair.nid) {
-						gl.SetRGB(colorMap[colorIdx][0], colorMap[colorIdx][1], colorMap[colorIdx][2])
-					} else {
-						gl.SetRGB(0.8, 0.8, 0.8)
-						z = 1.0
-					}
-				} else {
-					gl.SetRGB(0.8, 0.0, 0.0)
+				if kind == linkOther {
+					z = 1.0
+				}
+				gl.SetColor(s.linkColor(node, kind, nodeColor))
+				s.line(gl, node, pair, z, 0.0)
+			}
+		}
+
+		if s.isRing1DVisible() {
+			gl.SetColor(s.markColor(node, s.theme.Ring1D))
+			for _, nid := range node.required1D {
+				if pair, ok := nodes[nid]; ok {
+					s.line(gl, node, pair, 0.5, 0.5)
 				}
-				gl.Line3(node.x, node.y, z, pair.x, pair.y, 0)
+			}
+		}
+
+		// result of the validator
+		gl.SetColor(s.theme.Missing)
+		for _, nid := range node.missing2D {
+			if pair, ok := nodes[nid]; ok {
+				s.line(gl, node, pair, -0.5, -0.5)
+			}
+		}
+		gl.SetColor(s.theme.Extra)
+		for _, nid := range node.extra2D {
+			if pair, ok := nodes[nid]; ok {
+				s.line(gl, node, pair, -0.5, -0.5)
 			}
 		}
 	}
 
 	return nil
 }
+
+// line draws a line between nodes at the depth of each end
+func (s *Plane) line(gl *utils.GL, node1, node2 *Node, z1, z2 float64) {
+	x1, y1 := s.convertCoordinate(node1.x, node1.y)
+	x2, y2 := s.convertCoordinate(node2.x, node2.y)
+	gl.Line3(x1, y1, z1, x2, y2, z2)
+}
+
+func (s *Plane) position(node *Node) (float64, float64, bool) {
+	x, y := s.convertCoordinate(node.x, node.y)
+	return x, y, true
+}
+
+// setFocus moves the camera to draw the node at the center, nil node resets the camera
+func (s *Plane) setFocus(node *Node) {
+	s.camera = point2{}
+	if node != nil {
+		s.camera = point2{node.x, node.y}
+	}
+}
+
+func (s *Plane) convertCoordinate(xi, yi float64) (xo, yo float64) {
+	return xi - s.camera.x, yi - s.camera.y
+}
+
+func (s *Plane) drawVoronoi(gl *utils.GL, nodes map[string]*Node) {
+	enabled := enabledNodes(nodes)
+	points := make([]point2, len(enabled))
+	for i, node := range enabled {
+		points[i] = point2{node.x, node.y}
+	}
+
+	for i, cell := range voronoiPlane(points) {
+		vertices := make([]float64, 0, len(cell)*3)
+		for _, v := range cell {
+			x, y := s.convertCoordinate(v.x, v.y)
+			vertices = append(vertices, x, y, 0.999)
+		}
+		gl.SetColor(s.cellColor(enabled[i]))
+		gl.Polygon3(vertices)
+	}
+}
@@ -0,0 +1,294 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/llamerada-jp/colonio-simulator-view/pkg/utils"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// selectRadius is the distance in pixels to select the node by clicking
+	selectRadius = 8
+	// recordScrollLines is the count of lines scrolled by a key
+	recordScrollLines = 10
+)
+
+// positioner is implemented by drawers which can tell where the node is drawn,
+// x and y are the scene coordinate and ok is false if the node is not visible
+type positioner interface {
+	position(node *Node) (x, y float64, ok bool)
+}
+
+// recordBrowser is the state of the panel listing raw records of the current second
+type recordBrowser struct {
+	show bool
+	// level and message filter records, empty means no filter
+	level   string
+	message string
+	// levels seen in records to cycle the level filter
+	levels []string
+	scroll int
+	// records of the current second, and records got by the message filter
+	records         []utils.Record
+	filtered        []utils.Record
+	filteredTime    time.Time
+	filteredMessage string
+}
+
+// keepRecords keeps records of the current second for the panel
+func (b *recordBrowser) keepRecords(records []utils.Record) {
+	b.records = records
+	for _, record := range records {
+		if !contains(b.levels, record.Level) {
+			b.levels = append(b.levels, record.Level)
+			sort.Strings(b.levels)
+		}
+	}
+}
+
+// cycleLevel changes the level filter to the next level seen, it goes back to no filter after the last level
+func (b *recordBrowser) cycleLevel() {
+	next := ""
+	for idx, level := range b.levels {
+		if level == b.level {
+			if idx+1 < len(b.levels) {
+				next = b.levels[idx+1]
+			}
+			break
+		}
+	}
+	if len(b.level) == 0 && len(b.levels) != 0 {
+		next = b.levels[0]
+	}
+	b.level = next
+	b.scroll = 0
+	log.Printf("level of records: %s", filterName(b.level))
+}
+
+func (s *Model2D) setupRecordKeys() {
+	s.gl.OnKey('r', func() {
+		s.browser.show = !s.browser.show
+	})
+	s.gl.OnKey('e', s.browser.cycleLevel)
+	s.gl.OnKey('/', func() {
+		s.browser.show = true
+		s.gl.Prompt("message", s.browser.message, func(message string) {
+			s.browser.message = strings.TrimSpace(message)
+			s.browser.scroll = 0
+			log.Printf("message of records: %s", filterName(s.browser.message))
+		})
+	})
+	s.gl.OnKey('j', func() {
+		s.browser.scroll += recordScrollLines
+	})
+	s.gl.OnKey('k', func() {
+		s.browser.scroll -= recordScrollLines
+		if s.browser.scroll < 0 {
+			s.browser.scroll = 0
+		}
+	})
+	s.gl.OnKey(' ', func() {
+		s.paused = !s.paused
+	})
+	s.gl.OnClick(s.onClick)
+}
+
+// onClick marks the time by clicking on the timeline, or selects the node at the clicked position
+func (s *Model2D) onClick(x, y int) {
+	if s.markTimeline(x-s.gl.ViewLeft(s.view), y) {
+		return
+	}
+	s.selectNode(x, y)
+}
+
+// selectNode selects the nearest node to the clicked position, clicking far from nodes clears the selection
+func (s *Model2D) selectNode(x, y int) {
+	drawer, ok := s.drawer.(positioner)
+	if !ok {
+		return
+	}
+	width, height := s.gl.SceneSize()
+	x -= s.gl.ViewLeft(s.view)
+	if x < 0 || x >= width {
+		return
+	}
+
+	selected := ""
+	distance := math.Inf(1)
+	for nid, node := range s.nodes {
+		if !node.enable || s.isHidden(node) {
+			continue
+		}
+		px, py, ok := s.nodePixel(drawer, node, width, height)
+		if !ok {
+			continue
+		}
+		d := math.Hypot(px-float64(x), py-float64(y))
+		if d <= selectRadius && d < distance {
+			selected = nid
+			distance = d
+		}
+	}
+	if selected != s.selected {
+		s.browser.scroll = 0
+	}
+	s.selected = selected
+	if len(selected) != 0 {
+		log.Printf("selected node: %s", selected)
+	}
+}
+
+// nodePixel gets the position of the node in pixels of the window
+func (s *Model2D) nodePixel(drawer positioner, node *Node, width, height int) (float64, float64, bool) {
+	x, y, ok := drawer.position(node)
+	if !ok {
+		return 0, 0, false
+	}
+	return (x + 1.0) / 2.0 * float64(width), (1.0 - y) / 2.0 * float64(height), true
+}
+
+// drawSelection draws a frame around the selected node
+func (s *Model2D) drawSelection() {
+	drawer, ok := s.drawer.(positioner)
+	if !ok || len(s.selected) == 0 {
+		return
+	}
+	node, ok := s.nodes[s.selected]
+	if !ok || !node.enable || s.isHidden(node) {
+		return
+	}
+	width, height := s.gl.SceneSize()
+	x, y, ok := s.nodePixel(drawer, node, width, height)
+	if !ok {
+		return
+	}
+	r := float64(selectRadius)
+	s.gl.SetColor(s.theme.Text)
+	s.gl.Polyline([]float64{x - r, x + r, x + r, x - r, x - r}, []float64{y - r, y - r, y + r, y + r, y - r})
+}
+
+// currentRecords gets records of the current second filtered by the message, level and the selected node
+func (s *Model2D) currentRecords(current *time.Time) ([]utils.Record, error) {
+	b := &s.browser
+	records := b.records
+	if len(b.message) != 0 {
+		if !b.filteredTime.Equal(*current) || b.filteredMessage != b.message {
+			filtered, err := s.accessor.GetByTimeMessage(current, b.message)
+			if err != nil {
+				return nil, err
+			}
+			b.filtered = filtered
+			b.filteredTime = *current
+			b.filteredMessage = b.message
+		}
+		records = b.filtered
+	}
+
+	result := make([]utils.Record, 0, len(records))
+	for _, record := range records {
+		if len(b.level) != 0 && record.Level != b.level {
+			continue
+		}
+		if len(s.selected) != 0 && record.NID != s.selected {
+			continue
+		}
+		result = append(result, record)
+	}
+	return result, nil
+}
+
+// drawRecords draws the panel of records at the bottom half of the scene
+func (s *Model2D) drawRecords(current *time.Time) error {
+	records, err := s.currentRecords(current)
+	if err != nil {
+		return err
+	}
+
+	lines := make([]string, 0)
+	for _, record := range records {
+		lines = append(lines, fmt.Sprintf("%s %s %s %s:%d %s",
+			record.Time, record.Level, record.NID, record.File, record.Line, record.Message))
+		for _, line := range formatParam(record.Param) {
+			lines = append(lines, "  "+line)
+		}
+	}
+
+	width, height := s.gl.SceneSize()
+	top := height / 2
+	panelHeight := height - top - hudMargin*2 - timelineHeight
+	rows := panelHeight/utils.FontHeight - 1
+	columns := (width - hudMargin*4) / utils.FontWidth
+	if rows <= 0 || columns <= 0 {
+		return nil
+	}
+	if s.browser.scroll > len(lines)-rows {
+		s.browser.scroll = len(lines) - rows
+	}
+	if s.browser.scroll < 0 {
+		s.browser.scroll = 0
+	}
+
+	header := fmt.Sprintf("records %d  level:%s message:%s node:%s  line %d/%d",
+		len(records), filterName(s.browser.level), filterName(s.browser.message), filterName(s.selected),
+		s.browser.scroll, len(lines))
+
+	s.gl.SetColor(s.theme.Panel)
+	s.gl.Rect(hudMargin, top, width-hudMargin*2, panelHeight)
+	s.gl.SetColor(s.theme.Text)
+	visible := append([]string{header}, lines[s.browser.scroll:]...)
+	for idx, line := range visible {
+		if idx > rows {
+			break
+		}
+		if len(line) > columns {
+			line = line[:columns]
+		}
+		s.gl.Text(hudMargin*2, top+hudMargin+idx*utils.FontHeight, line)
+	}
+	return nil
+}
+
+// formatParam makes lines of pretty-printed JSON of the parameter
+func formatParam(param bson.Raw) []string {
+	if len(param) == 0 {
+		return nil
+	}
+	text := param.String()
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(text), "", "  "); err != nil {
+		return []string{text}
+	}
+	return strings.Split(buf.String(), "\n")
+}
+
+// filterName gets the name of the filter value to show, empty means all
+func filterName(value string) string {
+	if len(value) == 0 {
+		return "all"
+	}
+	return value
+}
@@ -0,0 +1,147 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/llamerada-jp/colonio-simulator-view/pkg/utils"
+)
+
+const ringRadius = 0.9
+
+// Ring is a drawer instance placing nodes on a circle by the order of nid for 1D routing
+type Ring struct {
+	painter
+	// positions of enabled nodes in the last frame
+	positions map[string]ringPosition
+}
+
+type ringPosition struct {
+	x float64
+	y float64
+}
+
+// NewRingDrawer make ring drawer instance
+func NewRingDrawer(detailLevel uint, theme *utils.Theme, coloring ColoringMode) *Ring {
+	return &Ring{
+		painter: newPainter(detailLevel, theme, coloring),
+	}
+}
+
+func (s *Ring) setup(gl *utils.GL) {
+	s.setupKeys(gl)
+}
+
+func (s *Ring) draw(gl *utils.GL, nodes map[string]*Node, current *time.Time) error {
+	s.prepare(nodes, current)
+
+	// place enabled nodes on the circle clockwise from the top
+	nids := make([]string, 0)
+	for nid, node := range nodes {
+		if node.enable {
+			nids = append(nids, nid)
+		}
+	}
+	sort.Strings(nids)
+	positions := make(map[string]ringPosition)
+	for idx, nid := range nids {
+		angle := math.Pi/2.0 - 2.0*math.Pi*float64(idx)/float64(len(nids))
+		positions[nid] = ringPosition{
+			x: ringRadius * math.Cos(angle),
+			y: ringRadius * math.Sin(angle),
+		}
+	}
+	s.positions = positions
+
+	for idx, nid := range nids {
+		node := nodes[nid]
+		pos := positions[nid]
+		nodeColor := s.nodeColor(node)
+		gl.SetColor(nodeColor)
+		gl.Point3(pos.x, pos.y, -1.0)
+		if s.isIsolated(node) {
+			gl.SetColor(s.markColor(node, s.theme.Isolated))
+			gl.Box3(pos.x, pos.y, -1.0, 14.0)
+		}
+
+		// required-1D links as chords
+		for _, pairNid := range node.required1D {
+			pairPos, ok := positions[pairNid]
+			if !ok {
+				continue
+			}
+			if nodes[pairNid].hasRequired1D(nid) {
+				gl.SetColor(nodeColor)
+			} else if s.detailLevel >= DetailOneWay {
+				gl.SetColor(s.markColor(node, s.theme.OneWayLink))
+			} else {
+				continue
+			}
+			gl.Line3(pos.x, pos.y, 0.0, pairPos.x, pairPos.y, 0.0)
+		}
+
+		if s.detailLevel >= DetailAllLinks {
+			gl.SetColor(s.markColor(node, s.theme.Link))
+			for _, pairNid := range node.links {
+				if pairPos, ok := positions[pairNid]; ok && !node.hasRequired1D(pairNid) {
+					gl.Line3(pos.x, pos.y, 1.0, pairPos.x, pairPos.y, 1.0)
+				}
+			}
+		}
+
+		// mark and connect the predecessor and successor missing from required-1D, and other nodes in it
+		if len(nids) < 2 {
+			continue
+		}
+		prev := nids[(idx+len(nids)-1)%len(nids)]
+		next := nids[(idx+1)%len(nids)]
+		mismatch := false
+		for _, expected := range []string{prev, next} {
+			if node.hasRequired1D(expected) {
+				continue
+			}
+			mismatch = true
+			pairPos := positions[expected]
+			gl.SetColor(s.theme.Missing)
+			gl.Line3(pos.x, pos.y, -0.5, pairPos.x, pairPos.y, -0.5)
+		}
+		for _, pairNid := range node.required1D {
+			if pairNid == prev || pairNid == next {
+				continue
+			}
+			mismatch = true
+			if pairPos, ok := positions[pairNid]; ok {
+				gl.SetColor(s.theme.Extra)
+				gl.Line3(pos.x, pos.y, -0.5, pairPos.x, pairPos.y, -0.5)
+			}
+		}
+		if mismatch {
+			gl.SetColor(s.theme.Missing)
+			gl.Box3(pos.x, pos.y, -1.0, 8.0)
+		}
+	}
+
+	return nil
+}
+
+func (s *Ring) position(node *Node) (float64, float64, bool) {
+	pos, ok := s.positions[node.nid]
+	return pos.x, pos.y, ok
+}
@@ -1,7 +1,6 @@
 package model2d
 
 import (
-	"log"
 	"math"
 	"time"
 
@@ -10,97 +9,192 @@ import (
 
 // Sphere is a drawer instance for sphere coordinate system
 type Sphere struct {
-	detailLevel uint
-}
-
-var sphereScale = 0.95
-var sphereColorMap = [][]float32{
-	{0.8, 0.0, 0.8},
-	{0.0, 0.2, 1.0},
-	{0.0, 0.8, 0.2},
-	{1.0, 0.6, 0.0},
+	painter
+	// camera rotates the sphere by the longitude and then by the latitude
+	camera point2
 }
 
 // NewSphereDrawer make sphere drawer instance
-func NewSphereDrawer(detailLevel uint) *Sphere {
+func NewSphereDrawer(detailLevel uint, theme *utils.Theme, coloring ColoringMode) *Sphere {
 	return &Sphere{
-		detailLevel: detailLevel,
+		painter: newPainter(detailLevel, theme, coloring),
 	}
 }
 
+func (s *Sphere) setup(gl *utils.GL) {
+	s.setupKeys(gl)
+	gl.OnKey('o', s.toggleVoronoi)
+}
+
 func (s *Sphere) draw(gl *utils.GL, nodes map[string]*Node, current *time.Time) error {
-	nodeCount := 0
-	seedCount := 0
-	onlyoneCount := 0
+	s.prepare(nodes, current)
+
+	if s.voronoi {
+		s.drawVoronoi(gl, nodes)
+	}
 
 	for _, node := range nodes {
 		if !node.enable {
 			continue
 		}
-		nodeCount++
 
-		colorIdx := node.group
-		if colorIdx >= len(colorMap) {
-			colorIdx = 0
-		}
+		nodeColor := s.nodeColor(node)
 		x, y, z := s.convertCoordinate(node.x, node.y)
-		gl.SetRGB(s.reduceColorByZ(colorMap[colorIdx], z))
+		gl.SetColor(s.reduceColorByZ(nodeColor, z))
 		gl.Point3(x, y, z)
 
 		if node.seedLinkStatus == LinkStatusOnline {
 			x, y, z := s.convertCoordinate(node.x, node.y)
-			gl.SetRGB(s.reduceColorByZ([]float32{1.0, 0.0, 0.0}, z))
+			gl.SetColor(s.reduceColorByZ(s.markColor(node, s.theme.Seed), z))
 			gl.Box3(x, y, z, 6.0)
-			seedCount++
 		}
 		if node.isOnlyone {
 			x, y, z := s.convertCoordinate(node.x, node.y)
-			gl.SetRGB(s.reduceColorByZ([]float32{1.0, 0.0, 0.0}, z))
+			gl.SetColor(s.reduceColorByZ(s.markColor(node, s.theme.Onlyone), z))
 			gl.Box3(x, y, z, 10.0)
-			onlyoneCount++
+		}
+		if s.isIsolated(node) {
+			gl.SetColor(s.reduceColorByZ(s.markColor(node, s.theme.Isolated), z))
+			gl.Box3(x, y, z, 14.0)
 		}
 
 		for _, link := range node.links {
 			if pair, ok := nodes[link]; ok {
-				var rgb []float32
-				if node.hasRequired2D(pair.nid) {
-					if pair.hasLink(node.nid) {
-						rgb = colorMap[colorIdx]
-					} else {
-						rgb = []float32{0.8, 0.0, 0.0}
-					}
-				} else {
-					if s.detailLevel >= 1 {
-						rgb = []float32{0.8, 0.8, 0.8}
-					} else {
-						continue
-					}
+				kind := s.linkKind(node, pair)
+				if !s.isVisible(kind) {
+					continue
 				}
 
 				x1, y1, z1 := s.convertCoordinate(node.x, node.y)
 				x2, y2, z2 := s.convertCoordinate(pair.x, pair.y)
-				gl.SetRGB(s.reduceColorByZ(rgb, (z1+z2)/2.0))
+				gl.SetColor(s.reduceColorByZ(s.linkColor(node, kind, nodeColor), (z1+z2)/2.0))
 				gl.Line3(x1, y1, z1, x2, y2, z2)
 			}
 		}
-	}
 
-	log.Printf("node: %d/%d  seed: %d/%d", nodeCount, len(nodes), onlyoneCount, seedCount)
+		if s.isRing1DVisible() {
+			for _, nid := range node.required1D {
+				if pair, ok := nodes[nid]; ok {
+					x1, y1, z1 := s.convertCoordinate(node.x, node.y)
+					x2, y2, z2 := s.convertCoordinate(pair.x, pair.y)
+					gl.SetColor(s.reduceColorByZ(s.markColor(node, s.theme.Ring1D), (z1+z2)/2.0))
+					gl.Line3(x1, y1, z1, x2, y2, z2)
+				}
+			}
+		}
+
+		// result of the validator
+		for _, nid := range node.missing2D {
+			if pair, ok := nodes[nid]; ok {
+				s.line(gl, node, pair, s.theme.Missing)
+			}
+		}
+		for _, nid := range node.extra2D {
+			if pair, ok := nodes[nid]; ok {
+				s.line(gl, node, pair, s.theme.Extra)
+			}
+		}
+	}
 
 	return nil
 }
 
-func (s *Sphere) reduceColorByZ(ci []float32, z float64) (r, g, b float32) {
+// position gets the position of the node on the front side of the sphere
+func (s *Sphere) position(node *Node) (float64, float64, bool) {
+	x, y, z := s.convertCoordinate(node.x, node.y)
+	return x, y, z <= 0.0
+}
+
+func (s *Sphere) line(gl *utils.GL, node1, node2 *Node, c utils.Color) {
+	x1, y1, z1 := s.convertCoordinate(node1.x, node1.y)
+	x2, y2, z2 := s.convertCoordinate(node2.x, node2.y)
+	gl.SetColor(s.reduceColorByZ(c, (z1+z2)/2.0))
+	gl.Line3(x1, y1, z1, x2, y2, z2)
+}
+
+// reduceColorByZ fades the color into the background as the position goes back
+func (s *Sphere) reduceColorByZ(c utils.Color, z float64) utils.Color {
 	rate := (float32(-z) + 1.0) / 1.2
-	r = 1.0 - ((1.0 - ci[0]) * rate)
-	g = 1.0 - ((1.0 - ci[1]) * rate)
-	b = 1.0 - ((1.0 - ci[2]) * rate)
-	return
+	return s.theme.Background.Mix(c, rate)
+}
+
+func (s *Sphere) drawVoronoi(gl *utils.GL, nodes map[string]*Node) {
+	// divide edges of cells to draw them along the sphere
+	const divisions = 4
+	// draw cells slightly inside of the sphere to keep nodes and links in front of them
+	const scale = 0.995
+
+	enabled := enabledNodes(nodes)
+	points := make([]point3, len(enabled))
+	for i, node := range enabled {
+		points[i] = sphericalPoint(node.x, node.y)
+	}
+
+	for i, cell := range voronoiSphere(points) {
+		if len(cell) < 3 {
+			continue
+		}
+		center := s.rotate(points[i])
+		vertices := []float64{center.x * scale, center.y * scale, center.z * scale}
+		for j, a := range cell {
+			b := cell[(j+1)%len(cell)]
+			for k := 0; k < divisions; k++ {
+				t := float64(k) / divisions
+				v := s.rotate(normalize(point3{
+					x: a.x + (b.x-a.x)*t,
+					y: a.y + (b.y-a.y)*t,
+					z: a.z + (b.z-a.z)*t,
+				}))
+				vertices = append(vertices, v.x*scale, v.y*scale, v.z*scale)
+			}
+		}
+		vertices = append(vertices, vertices[3], vertices[4], vertices[5])
+		gl.SetColor(s.reduceColorByZ(s.cellColor(enabled[i]), center.z))
+		gl.Polygon3(vertices)
+	}
+}
+
+// setFocus rotates the sphere to draw the node at the front, nil node resets the rotation
+func (s *Sphere) setFocus(node *Node) {
+	s.camera = point2{}
+	if node != nil {
+		// the front of the sphere is the longitude -pi/2 and the latitude 0
+		s.camera = point2{node.x + math.Pi/2.0, node.y}
+	}
+}
+
+// rotate moves the point on the sphere by the camera
+func (s *Sphere) rotate(p point3) point3 {
+	if s.camera.x != 0.0 {
+		c := math.Cos(s.camera.x)
+		n := math.Sin(s.camera.x)
+		// rotate around the y axis to decrease the longitude
+		p = point3{
+			x: p.x*c + p.z*n,
+			y: p.y,
+			z: p.z*c - p.x*n,
+		}
+	}
+	if s.camera.y != 0.0 {
+		c := math.Cos(s.camera.y)
+		n := math.Sin(s.camera.y)
+		// rotate around the x axis to decrease the latitude of points at the front
+		p = point3{
+			x: p.x,
+			y: p.y*c + p.z*n,
+			z: p.z*c - p.y*n,
+		}
+	}
+	return p
 }
 
 func (s *Sphere) convertCoordinate(xi, yi float64) (xo, yo, zo float64) {
-	xo = math.Cos(xi) * math.Cos(yi)
-	yo = math.Sin(yi)
-	zo = math.Sin(xi) * math.Cos(yi)
-	return
+	p := s.rotate(sphericalPoint(xi, yi))
+	return p.x, p.y, p.z
+}
+
+// sphereFront moves the depth slightly to the front to keep overlays in front of links,
+// the depth stays in the range of -1 to 1
+func sphereFront(z float64) float64 {
+	return z*0.99 - 0.01
 }
@@ -0,0 +1,183 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"sort"
+	"time"
+
+	"github.com/llamerada-jp/colonio-simulator-view/pkg/utils"
+)
+
+const (
+	// swimlaneLeft is the left of bands, the space at the left side is for labels
+	swimlaneLeft = -0.8
+	// swimlaneLabelLength is the count of characters of nid drawn as the label
+	swimlaneLabelLength = 8
+)
+
+// statusChange is the status of the node since the time
+type statusChange struct {
+	time           time.Time
+	enable         bool
+	seedLinkStatus int
+	nodeLinkStatus int
+	authStatus     int
+	isOnlyone      bool
+}
+
+// recordStatus appends the current status of the node to the history if it is changed
+func (n *Node) recordStatus(t time.Time) {
+	change := statusChange{
+		time:           t,
+		enable:         n.enable,
+		seedLinkStatus: n.seedLinkStatus,
+		nodeLinkStatus: n.nodeLinkStatus,
+		authStatus:     n.authStatus,
+		isOnlyone:      n.isOnlyone,
+	}
+	if len(n.statusHistory) != 0 {
+		last := n.statusHistory[len(n.statusHistory)-1]
+		last.time = t
+		if last == change {
+			return
+		}
+	}
+	n.statusHistory = append(n.statusHistory, change)
+}
+
+// Swimlane is a drawer instance drawing the history of status of each node as a row
+type Swimlane struct {
+	theme *utils.Theme
+	// seconds is the time range of the chart, 0 means from the first record
+	seconds int
+}
+
+// NewSwimlaneDrawer make swimlane drawer instance
+func NewSwimlaneDrawer(theme *utils.Theme, seconds int) *Swimlane {
+	return &Swimlane{
+		theme:   theme,
+		seconds: seconds,
+	}
+}
+
+func (s *Swimlane) setup(gl *utils.GL) {
+}
+
+// draw rows of nodes ordered by the time joined, each row has bands of seed link, node link and auth status
+// from the top, and a thin band at the bottom while the node is only one
+func (s *Swimlane) draw(gl *utils.GL, nodes map[string]*Node, current *time.Time) error {
+	rows := make([]*Node, 0, len(nodes))
+	for _, node := range nodes {
+		if len(node.statusHistory) != 0 {
+			rows = append(rows, node)
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if !rows[i].firstSeen.Equal(rows[j].firstSeen) {
+			return rows[i].firstSeen.Before(rows[j].firstSeen)
+		}
+		return rows[i].nid < rows[j].nid
+	})
+
+	start := current.Add(-time.Duration(s.seconds) * time.Second)
+	if s.seconds <= 0 {
+		start = rows[0].statusHistory[0].time
+	}
+	span := current.Sub(start).Seconds()
+	if span <= 0 {
+		span = 1.0
+	}
+	toX := func(t time.Time) float64 {
+		if t.Before(start) {
+			t = start
+		}
+		return swimlaneLeft + (1.0-swimlaneLeft)*t.Sub(start).Seconds()/span
+	}
+
+	rowHeight := 2.0 / float64(len(rows))
+	bandHeight := rowHeight / 4.0
+	for idx, node := range rows {
+		top := 1.0 - rowHeight*float64(idx)
+		for i, change := range node.statusHistory {
+			if !change.enable {
+				continue
+			}
+			end := *current
+			if i+1 < len(node.statusHistory) {
+				end = node.statusHistory[i+1].time
+			}
+			if end.Before(start) {
+				continue
+			}
+			x1 := toX(change.time)
+			x2 := toX(end)
+
+			bands := []utils.Color{
+				statusColor(s.theme.LinkStatus, change.seedLinkStatus),
+				statusColor(s.theme.LinkStatus, change.nodeLinkStatus),
+				statusColor(s.theme.AuthStatus, change.authStatus),
+			}
+			for b, c := range bands {
+				y1 := top - bandHeight*float64(b)
+				s.band(gl, c, x1, x2, y1, y1-bandHeight)
+			}
+			if change.isOnlyone {
+				y1 := top - bandHeight*3.0
+				s.band(gl, s.theme.Onlyone, x1, x2, y1, y1-bandHeight/2.0)
+			}
+		}
+	}
+
+	s.drawLabels(gl, rows)
+	return nil
+}
+
+// band fills the rectangle, it has the width of one pixel at least to show momentary status
+func (s *Swimlane) band(gl *utils.GL, c utils.Color, x1, x2, y1, y2 float64) {
+	width, _ := gl.SceneSize()
+	if minWidth := 2.0 / float64(width); x2-x1 < minWidth {
+		x2 = x1 + minWidth
+	}
+	gl.SetColor(c)
+	gl.Polygon3([]float64{
+		x1, y1, 0.0,
+		x2, y1, 0.0,
+		x2, y2, 0.0,
+		x1, y2, 0.0,
+	})
+}
+
+// drawLabels draws nid at the left side of each row if rows are high enough
+func (s *Swimlane) drawLabels(gl *utils.GL, rows []*Node) {
+	_, height := gl.SceneSize()
+	rowPixels := height / len(rows)
+	if rowPixels < utils.FontHeight {
+		return
+	}
+	gl.SetColor(s.theme.Text)
+	for idx, node := range rows {
+		label := node.nid
+		if len(label) > swimlaneLabelLength {
+			label = label[:swimlaneLabelLength]
+		}
+		gl.Text(hudMargin, idx*rowPixels+(rowPixels-utils.FontHeight)/2, label)
+	}
+}
@@ -0,0 +1,224 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"github.com/llamerada-jp/colonio-simulator-view/pkg/utils"
+)
+
+const (
+	// defaultTrailLifetime is the time of trails drawn by the key without the option
+	defaultTrailLifetime = 10 * time.Second
+	// velocityWindow is the count of seconds to average the velocity of nodes
+	velocityWindow = 3
+	// velocityScale is the count of seconds of the movement drawn as the velocity arrow
+	velocityScale = 5.0
+	// arrowHead is the length of the head of velocity arrows in the scene coordinate
+	arrowHead = 0.02
+	// arcDivisions is the count of segments to draw a great-circle arc on the sphere
+	arcDivisions = 4
+)
+
+// trailPoint is the position of the node at the time
+type trailPoint struct {
+	time time.Time
+	x    float64
+	y    float64
+}
+
+// trailDrawer is implemented by drawers which can draw trails and velocity of nodes
+type trailDrawer interface {
+	drawTrails(gl *utils.GL, nodes map[string]*Node, current *time.Time, lifetime time.Duration)
+}
+
+// SetTrails sets seconds of trails of nodes drawn with velocity arrows, 0 means no trails
+func (s *Model2D) SetTrails(seconds int) {
+	s.trailSeconds = seconds
+	s.showTrails = seconds > 0
+}
+
+func (s *Model2D) setupTrailKeys() {
+	if _, ok := s.drawer.(trailDrawer); !ok {
+		return
+	}
+	s.gl.OnKey('t', func() {
+		s.showTrails = !s.showTrails
+		log.Printf("trails: %t", s.showTrails)
+	})
+}
+
+// trailLifetime gets the time of trails to draw
+func (s *Model2D) trailLifetime() time.Duration {
+	if s.trailSeconds > 0 {
+		return time.Duration(s.trailSeconds) * time.Second
+	}
+	return defaultTrailLifetime
+}
+
+// recordPositions appends the position of each enabled node to its history,
+// the history is kept for the longer of trails and the trail of the focused node
+func (s *Model2D) recordPositions(current *time.Time) {
+	lifetime := s.trailLifetime()
+	if lifetime < focusTrailLifetime {
+		lifetime = focusTrailLifetime
+	}
+	for _, node := range s.nodes {
+		if node.enable {
+			node.positions = append(node.positions, trailPoint{*current, node.x, node.y})
+		}
+		drop := 0
+		for drop < len(node.positions) && current.Sub(node.positions[drop].time) > lifetime {
+			drop++
+		}
+		node.positions = node.positions[drop:]
+	}
+}
+
+func (s *Model2D) drawTrails(nodes map[string]*Node, current *time.Time) {
+	if drawer, ok := s.drawer.(trailDrawer); ok {
+		drawer.drawTrails(s.gl, nodes, current, s.trailLifetime())
+	}
+}
+
+// recentTrail gets positions of the node in the lifetime
+func recentTrail(node *Node, current time.Time, lifetime time.Duration) []trailPoint {
+	trail := node.positions
+	for len(trail) != 0 && current.Sub(trail[0].time) > lifetime {
+		trail = trail[1:]
+	}
+	return trail
+}
+
+// velocity gets the movement per second averaged over the last seconds, ok is false if the node did not move
+func velocity(trail []trailPoint) (from, to trailPoint, ok bool) {
+	if len(trail) < 2 {
+		return trailPoint{}, trailPoint{}, false
+	}
+	from = trail[0]
+	if len(trail) > velocityWindow {
+		from = trail[len(trail)-1-velocityWindow]
+	}
+	to = trail[len(trail)-1]
+	return from, to, from.x != to.x || from.y != to.y
+}
+
+func (s *Plane) drawTrails(gl *utils.GL, nodes map[string]*Node, current *time.Time, lifetime time.Duration) {
+	for _, node := range nodes {
+		if !node.enable {
+			continue
+		}
+		nodeColor := s.nodeColor(node)
+		trail := recentTrail(node, *current, lifetime)
+		for i := 1; i < len(trail); i++ {
+			x1, y1 := s.convertCoordinate(trail[i-1].x, trail[i-1].y)
+			x2, y2 := s.convertCoordinate(trail[i].x, trail[i].y)
+			gl.SetColor(s.trailColor(nodeColor, trail[i].time, *current, lifetime))
+			gl.Line3(x1, y1, -0.7, x2, y2, -0.7)
+		}
+
+		from, to, ok := velocity(trail)
+		if !ok {
+			continue
+		}
+		seconds := to.time.Sub(from.time).Seconds()
+		vx := (to.x - from.x) / seconds * velocityScale
+		vy := (to.y - from.y) / seconds * velocityScale
+		x1, y1 := s.convertCoordinate(node.x, node.y)
+		x2, y2 := x1+vx, y1+vy
+		angle := math.Atan2(vy, vx)
+		gl.SetColor(nodeColor)
+		gl.Line3(x1, y1, -0.7, x2, y2, -0.7)
+		for _, side := range []float64{-1.0, 1.0} {
+			head := angle + math.Pi + side*math.Pi/6.0
+			gl.Line3(x2, y2, -0.7, x2+arrowHead*math.Cos(head), y2+arrowHead*math.Sin(head), -0.7)
+		}
+	}
+}
+
+func (s *Sphere) drawTrails(gl *utils.GL, nodes map[string]*Node, current *time.Time, lifetime time.Duration) {
+	for _, node := range nodes {
+		if !node.enable {
+			continue
+		}
+		nodeColor := s.nodeColor(node)
+		trail := recentTrail(node, *current, lifetime)
+		for i := 1; i < len(trail); i++ {
+			s.arc(gl, sphericalPoint(trail[i-1].x, trail[i-1].y), sphericalPoint(trail[i].x, trail[i].y),
+				s.trailColor(nodeColor, trail[i].time, *current, lifetime))
+		}
+
+		from, to, ok := velocity(trail)
+		if !ok {
+			continue
+		}
+		// rotate the current position along the great circle of the movement
+		p1 := sphericalPoint(from.x, from.y)
+		p2 := sphericalPoint(to.x, to.y)
+		axis := normalize(cross(p1, p2))
+		if norm(axis) < 1e-9 {
+			continue
+		}
+		speed := math.Acos(math.Max(-1.0, math.Min(1.0, dot(p1, p2)))) / to.time.Sub(from.time).Seconds()
+		start := sphericalPoint(node.x, node.y)
+		end := rotateAround(start, axis, speed*velocityScale)
+		s.arc(gl, start, end, nodeColor)
+
+		// the head is on the tangent plane at the end of the arrow
+		tangent := normalize(cross(axis, end))
+		for _, side := range []float64{-1.0, 1.0} {
+			head := normalize(point3{
+				x: end.x - (tangent.x*math.Cos(math.Pi/6.0)+axis.x*side*math.Sin(math.Pi/6.0))*arrowHead,
+				y: end.y - (tangent.y*math.Cos(math.Pi/6.0)+axis.y*side*math.Sin(math.Pi/6.0))*arrowHead,
+				z: end.z - (tangent.z*math.Cos(math.Pi/6.0)+axis.z*side*math.Sin(math.Pi/6.0))*arrowHead,
+			})
+			s.arc(gl, end, head, nodeColor)
+		}
+	}
+}
+
+// arc draws the great-circle arc between points on the sphere
+func (s *Sphere) arc(gl *utils.GL, a, b point3, c utils.Color) {
+	prev := s.rotate(a)
+	for k := 1; k <= arcDivisions; k++ {
+		t := float64(k) / arcDivisions
+		p := s.rotate(normalize(point3{
+			x: a.x + (b.x-a.x)*t,
+			y: a.y + (b.y-a.y)*t,
+			z: a.z + (b.z-a.z)*t,
+		}))
+		gl.SetColor(s.reduceColorByZ(c, (prev.z+p.z)/2.0))
+		gl.Line3(prev.x, prev.y, sphereFront(prev.z), p.x, p.y, sphereFront(p.z))
+		prev = p
+	}
+}
+
+// rotateAround rotates the point around the unit axis by Rodrigues' rotation formula
+func rotateAround(p, axis point3, angle float64) point3 {
+	c := math.Cos(angle)
+	n := math.Sin(angle)
+	q := cross(axis, p)
+	d := dot(axis, p) * (1.0 - c)
+	return point3{
+		x: p.x*c + q.x*n + axis.x*d,
+		y: p.y*c + q.y*n + axis.y*d,
+		z: p.z*c + q.z*n + axis.z*d,
+	}
+}
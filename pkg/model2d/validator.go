@@ -0,0 +1,150 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"fmt"
+	"log"
+	"sort"
+)
+
+// Validator checks required-2D of each node with the Delaunay triangulation of node positions
+type Validator struct {
+	enable      bool
+	triangulate func(nodes []*Node) []triangle
+	// reported keeps the last reported mismatch of each node to log only changes
+	reported map[string]string
+	missing  int
+	extra    int
+}
+
+// NewPlaneValidator makes a validator using planar Delaunay triangulation
+func NewPlaneValidator(enable bool) *Validator {
+	return &Validator{
+		enable: enable,
+		triangulate: func(nodes []*Node) []triangle {
+			points := make([]point2, len(nodes))
+			for i, node := range nodes {
+				points[i] = point2{node.x, node.y}
+			}
+			return delaunayPlane(points)
+		},
+		reported: make(map[string]string),
+	}
+}
+
+// NewSphereValidator makes a validator using spherical Delaunay triangulation
+func NewSphereValidator(enable bool) *Validator {
+	return &Validator{
+		enable: enable,
+		triangulate: func(nodes []*Node) []triangle {
+			points := make([]point3, len(nodes))
+			for i, node := range nodes {
+				points[i] = sphericalPoint(node.x, node.y)
+			}
+			return delaunaySphere(points)
+		},
+		reported: make(map[string]string),
+	}
+}
+
+func (v *Validator) toggle(nodes map[string]*Node) {
+	v.enable = !v.enable
+	log.Printf("validator: %t", v.enable)
+	if !v.enable {
+		v.clear(nodes)
+	}
+}
+
+func (v *Validator) clear(nodes map[string]*Node) {
+	for _, node := range nodes {
+		node.missing2D = nil
+		node.extra2D = nil
+	}
+	v.reported = make(map[string]string)
+	v.missing = 0
+	v.extra = 0
+}
+
+// validate sets missing and extra required-2D entries to each enabled node
+func (v *Validator) validate(nodes map[string]*Node) {
+	if !v.enable {
+		return
+	}
+	for _, node := range nodes {
+		node.missing2D = nil
+		node.extra2D = nil
+	}
+	v.missing = 0
+	v.extra = 0
+
+	// fix the order to get the same triangulation for the same positions
+	enabled := enabledNodes(nodes)
+
+	var neighbors []map[int]bool
+	if len(enabled) < 4 {
+		// all nodes should be neighbors for each other when the triangulation is not possible
+		neighbors = make([]map[int]bool, len(enabled))
+		for i := range enabled {
+			neighbors[i] = make(map[int]bool)
+			for j := range enabled {
+				if i != j {
+					neighbors[i][j] = true
+				}
+			}
+		}
+	} else {
+		neighbors = neighborsOf(v.triangulate(enabled), len(enabled))
+	}
+
+	for i, node := range enabled {
+		expected := make(map[string]bool)
+		for j := range neighbors[i] {
+			expected[enabled[j].nid] = true
+		}
+		for nid := range expected {
+			if !node.hasRequired2D(nid) {
+				node.missing2D = append(node.missing2D, nid)
+			}
+		}
+		for _, nid := range node.required2D {
+			if !expected[nid] {
+				node.extra2D = append(node.extra2D, nid)
+			}
+		}
+		sort.Strings(node.missing2D)
+		sort.Strings(node.extra2D)
+		v.missing += len(node.missing2D)
+		v.extra += len(node.extra2D)
+
+		v.report(node)
+	}
+}
+
+// report logs the mismatch of the node only when it is changed, nodes becoming valid are not logged
+func (v *Validator) report(node *Node) {
+	if len(node.missing2D) == 0 && len(node.extra2D) == 0 {
+		delete(v.reported, node.nid)
+		return
+	}
+	message := fmt.Sprintf("missing %v extra %v", node.missing2D, node.extra2D)
+	if message == v.reported[node.nid] {
+		return
+	}
+	log.Printf("validate %s: %s", node.nid, message)
+	v.reported[node.nid] = message
+}
@@ -0,0 +1,81 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidatorPlane(t *testing.T) {
+	// corners of the square and the center, each corner is adjacent to two corners and the center
+	nodes := map[string]*Node{
+		"a": {enable: true, nid: "a", x: 0, y: 0, required2D: []string{"e", "c", "b"}},
+		"b": {enable: true, nid: "b", x: 1, y: 0, required2D: []string{"a", "c", "e"}},
+		"c": {enable: true, nid: "c", x: 1, y: 1, required2D: []string{"b", "d", "e"}},
+		"d": {enable: true, nid: "d", x: 0, y: 1, required2D: []string{"a", "c", "e"}},
+		"e": {enable: true, nid: "e", x: 0.5, y: 0.4},
+		// disabled nodes are not validated and not expected
+		"f": {nid: "f", x: 0.5, y: 0.5, required2D: []string{"a"}},
+	}
+	nodes["e"].required2D = []string{"a", "b", "c", "d", "f"}
+
+	v := NewPlaneValidator(true)
+	v.validate(nodes)
+
+	tests := []struct {
+		nid     string
+		missing []string
+		extra   []string
+	}{
+		{"a", []string{"d"}, []string{"c"}},
+		{"b", nil, nil},
+		{"c", nil, nil},
+		{"d", nil, nil},
+		{"e", nil, []string{"f"}},
+		{"f", nil, nil},
+	}
+	for _, tt := range tests {
+		node := nodes[tt.nid]
+		if !reflect.DeepEqual(node.missing2D, tt.missing) || !reflect.DeepEqual(node.extra2D, tt.extra) {
+			t.Errorf("%s: missing %v extra %v, want missing %v extra %v",
+				tt.nid, node.missing2D, node.extra2D, tt.missing, tt.extra)
+		}
+	}
+	if v.missing != 1 || v.extra != 2 {
+		t.Errorf("missing %d extra %d, want 1 and 2", v.missing, v.extra)
+	}
+
+	v.toggle(nodes)
+	if nodes["a"].missing2D != nil || v.missing != 0 {
+		t.Errorf("results are not cleared by disabling the validator")
+	}
+}
+
+func TestValidatorFewNodes(t *testing.T) {
+	nodes := map[string]*Node{
+		"a": {enable: true, nid: "a", required2D: []string{"b"}},
+		"b": {enable: true, nid: "b", x: 1, required2D: []string{"a", "c"}},
+		"c": {enable: true, nid: "c", y: 1, required2D: []string{"a", "b"}},
+	}
+	v := NewSphereValidator(true)
+	v.validate(nodes)
+	// all nodes are neighbors for each other without the triangulation
+	if !reflect.DeepEqual(nodes["a"].missing2D, []string{"c"}) || nodes["b"].missing2D != nil {
+		t.Errorf("missing %v and %v", nodes["a"].missing2D, nodes["b"].missing2D)
+	}
+}
@@ -0,0 +1,131 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"math"
+	"sort"
+)
+
+// voronoiPlane computes the Voronoi cell of each point clipped by the square from -1 to 1
+func voronoiPlane(points []point2) [][]point2 {
+	n := len(points)
+	var neighbors []map[int]bool
+	if n < 4 {
+		neighbors = make([]map[int]bool, n)
+		for i := range points {
+			neighbors[i] = make(map[int]bool)
+			for j := range points {
+				if i != j {
+					neighbors[i][j] = true
+				}
+			}
+		}
+	} else {
+		neighbors = neighborsOf(delaunayPlane(points), n)
+	}
+
+	cells := make([][]point2, n)
+	for i, p := range points {
+		cell := []point2{{-1.0, -1.0}, {1.0, -1.0}, {1.0, 1.0}, {-1.0, 1.0}}
+		// the cell is bounded only by bisectors with Delaunay neighbors
+		for j := range neighbors[i] {
+			cell = clipByBisector(cell, p, points[j])
+		}
+		cells[i] = cell
+	}
+	return cells
+}
+
+// clipByBisector keeps the part of the convex polygon closer to a than b
+func clipByBisector(polygon []point2, a, b point2) []point2 {
+	nx, ny := b.x-a.x, b.y-a.y
+	mx, my := (a.x+b.x)/2.0, (a.y+b.y)/2.0
+	side := func(q point2) float64 {
+		return (q.x-mx)*nx + (q.y-my)*ny
+	}
+
+	result := make([]point2, 0, len(polygon)+1)
+	for i, current := range polygon {
+		next := polygon[(i+1)%len(polygon)]
+		sc, sn := side(current), side(next)
+		if sc <= 0 {
+			result = append(result, current)
+		}
+		if (sc < 0 && sn > 0) || (sc > 0 && sn < 0) {
+			t := sc / (sc - sn)
+			result = append(result, point2{
+				x: current.x + (next.x-current.x)*t,
+				y: current.y + (next.y-current.y)*t,
+			})
+		}
+	}
+	return result
+}
+
+// voronoiSphere computes the Voronoi cell of each point on the unit sphere,
+// vertices of each cell are ordered around the point
+func voronoiSphere(points []point3) [][]point3 {
+	faces := delaunaySphere(points)
+	if len(faces) == 0 {
+		return nil
+	}
+
+	// vertices of the cells are circumcenters of the faces on the sphere
+	centers := make([]point3, len(faces))
+	incident := make([][]int, len(points))
+	for i, f := range faces {
+		centers[i] = normalize(faceNormal(points, f))
+		for _, v := range f {
+			incident[v] = append(incident[v], i)
+		}
+	}
+
+	cells := make([][]point3, len(points))
+	for i, p := range points {
+		// sort the vertices by the angle on the tangent plane at the point
+		u := cross(p, point3{1.0, 0.0, 0.0})
+		if norm(u) < 0.1 {
+			u = cross(p, point3{0.0, 1.0, 0.0})
+		}
+		u = normalize(u)
+		v := cross(p, u)
+		angles := make(map[int]float64)
+		for _, f := range incident[i] {
+			d := sub(centers[f], p)
+			angles[f] = math.Atan2(dot(d, v), dot(d, u))
+		}
+		sort.Slice(incident[i], func(a, b int) bool {
+			return angles[incident[i][a]] < angles[incident[i][b]]
+		})
+
+		cell := make([]point3, len(incident[i]))
+		for j, f := range incident[i] {
+			cell[j] = centers[f]
+		}
+		cells[i] = cell
+	}
+	return cells
+}
+
+func normalize(a point3) point3 {
+	l := norm(a)
+	if l == 0 {
+		return a
+	}
+	return point3{a.x / l, a.y / l, a.z / l}
+}
@@ -0,0 +1,85 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package model2d
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestVoronoiPlane(t *testing.T) {
+	points := []point2{{-0.5, -0.5}, {0.5, -0.5}, {0.5, 0.5}, {-0.5, 0.5}, {0.1, 0.2}}
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 30; i++ {
+		points = append(points, point2{random.Float64()*2.0 - 1.0, random.Float64()*2.0 - 1.0})
+	}
+
+	cells := voronoiPlane(points)
+	if len(cells) != len(points) {
+		t.Fatalf("%d cells for %d points", len(cells), len(points))
+	}
+	area := 0.0
+	for i, cell := range cells {
+		for j, v := range cell {
+			next := cell[(j+1)%len(cell)]
+			area += (v.x*next.y - next.x*v.y) / 2.0
+			// vertices of the cell are not closer to other points
+			d := math.Hypot(v.x-points[i].x, v.y-points[i].y)
+			for k, p := range points {
+				if math.Hypot(v.x-p.x, v.y-p.y) < d-1e-9 {
+					t.Errorf("vertex %v of the cell %d is closer to %d", v, i, k)
+				}
+			}
+		}
+	}
+	// cells cover the square from -1 to 1
+	if math.Abs(area-4.0) > 1e-9 {
+		t.Errorf("total area = %f, want 4", area)
+	}
+}
+
+func TestVoronoiSphere(t *testing.T) {
+	octahedron := []point3{{1, 0, 0}, {-1, 0, 0}, {0, 1, 0}, {0, -1, 0}, {0, 0, 1}, {0, 0, -1}}
+	for i, cell := range voronoiSphere(octahedron) {
+		if len(cell) != 4 {
+			t.Errorf("cell %d of the octahedron has %d vertices, want 4", i, len(cell))
+		}
+	}
+
+	random := rand.New(rand.NewSource(1))
+	points := make([]point3, 100)
+	for i := range points {
+		points[i] = sphericalPoint(random.Float64()*2.0*math.Pi, math.Asin(random.Float64()*2.0-1.0))
+	}
+	for i, cell := range voronoiSphere(points) {
+		if len(cell) < 3 {
+			t.Errorf("cell %d has %d vertices", i, len(cell))
+		}
+		for _, v := range cell {
+			if math.Abs(norm(v)-1.0) > 1e-9 {
+				t.Errorf("vertex %v of the cell %d is not on the sphere", v, i)
+			}
+			d := dot(v, points[i])
+			for k, p := range points {
+				if dot(v, p) > d+1e-9 {
+					t.Errorf("vertex %v of the cell %d is closer to %d", v, i, k)
+				}
+			}
+		}
+	}
+}
@@ -18,6 +18,7 @@ package utils
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -32,8 +33,9 @@ const (
 
 // Accessor contain mongodb client and collections
 type Accessor struct {
-	client     *mongo.Client
-	collection *mongo.Collection
+	client       *mongo.Client
+	collection   *mongo.Collection
+	errorHandler func(*RecordError) error
 }
 
 // Record corresponds to one record in the log.
@@ -48,6 +50,34 @@ type Record struct {
 	TimeNtv time.Time
 }
 
+// RecordError is the error about a record, it contains where the record was output
+type RecordError struct {
+	NID     string
+	File    string
+	Line    int
+	Message string
+	Err     error
+}
+
+// NewRecordError makes the error about the record
+func NewRecordError(record *Record, err error) *RecordError {
+	return &RecordError{
+		NID:     record.NID,
+		File:    record.File,
+		Line:    record.Line,
+		Message: record.Message,
+		Err:     err,
+	}
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("%s:%d nid:%s message:%q %v", e.File, e.Line, e.NID, e.Message, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
 // NewAccessor makes new connection to mongoDB using target URI and etc
 func NewAccessor(uri, database, collection string) (*Accessor, error) {
 	// make context
@@ -71,6 +101,12 @@ func NewAccessor(uri, database, collection string) (*Accessor, error) {
 	}, nil
 }
 
+// SetErrorHandler sets the handler called for bad records, the record is skipped if the handler returns nil.
+// Getting records fails by the bad record if no handler is set.
+func (acc *Accessor) SetErrorHandler(handler func(*RecordError) error) {
+	acc.errorHandler = handler
+}
+
 // GetEarliestTime gets the timestamp of the earliest record in the DB
 func (acc *Accessor) GetEarliestTime() (*time.Time, error) {
 	var result Record
@@ -82,11 +118,9 @@ func (acc *Accessor) GetEarliestTime() (*time.Time, error) {
 		return nil, err
 	}
 
-	// drop timezone data
-	result.TimeNtv, err = time.Parse(timeFormat, result.Time[0:19])
-
+	result.TimeNtv, err = parseTime(result.Time)
 	if err != nil {
-		return nil, err
+		return nil, NewRecordError(&result, err)
 	}
 	return &result.TimeNtv, nil
 }
@@ -102,11 +136,9 @@ func (acc *Accessor) GetLastTime() (*time.Time, error) {
 		return nil, err
 	}
 
-	// drop timezone data
-	result.TimeNtv, err = time.Parse(timeFormat, result.Time[0:19])
-
+	result.TimeNtv, err = parseTime(result.Time)
 	if err != nil {
-		return nil, err
+		return nil, NewRecordError(&result, err)
 	}
 	return &result.TimeNtv, nil
 }
@@ -124,21 +156,11 @@ func (acc *Accessor) GetByTime(t *time.Time) ([]Record, error) {
 		return nil, err
 	}
 
-	results := make([]Record, 0)
-	for cur.Next(context.Background()) {
-		var result Record
-		if err = cur.Decode(&result); err != nil {
-			return nil, err
-		}
-		// drop timezone data
-		result.TimeNtv, err = time.Parse(timeFormat, result.Time[0:19])
-
-		results = append(results, result)
-	}
-	return results, nil
+	return acc.decodeRecords(cur, acc.handleError)
 }
 
-// GetByTimeMessage gets records having specified time and message
+// GetByTimeMessage gets records having specified time and message, bad records are skipped without the error
+// handler since they are passed to it by GetByTime for the same time
 func (acc *Accessor) GetByTimeMessage(t *time.Time, message string) ([]Record, error) {
 	option := options.Find().SetSort(bson.M{"time": 1})
 	filter := bson.M{
@@ -152,18 +174,49 @@ func (acc *Accessor) GetByTimeMessage(t *time.Time, message string) ([]Record, e
 		return nil, err
 	}
 
+	return acc.decodeRecords(cur, skipError)
+}
+
+// decodeRecords decodes records from the cursor, bad records are passed to the handler
+func (acc *Accessor) decodeRecords(cur *mongo.Cursor, handleError func(*RecordError) error) ([]Record, error) {
+	defer cur.Close(context.Background())
+
 	results := make([]Record, 0)
 	for cur.Next(context.Background()) {
 		var result Record
-		if err = cur.Decode(&result); err != nil {
-			return nil, err
+		err := cur.Decode(&result)
+		if err == nil {
+			result.TimeNtv, err = parseTime(result.Time)
+		}
+		if err != nil {
+			if err = handleError(NewRecordError(&result, err)); err != nil {
+				return nil, err
+			}
+			continue
 		}
-		// drop timezone data
-		result.TimeNtv, err = time.Parse(timeFormat, result.Time[0:19])
-
 		results = append(results, result)
 	}
-	return results, nil
+	return results, cur.Err()
+}
+
+func (acc *Accessor) handleError(err *RecordError) error {
+	if acc.errorHandler == nil {
+		return err
+	}
+	return acc.errorHandler(err)
+}
+
+// skipError skips bad records without reporting them
+func skipError(err *RecordError) error {
+	return nil
+}
+
+// parseTime parses the time of the record dropping timezone data
+func parseTime(s string) (time.Time, error) {
+	if len(s) < len(timeFormat) {
+		return time.Time{}, fmt.Errorf("time should start with the format %s: %q", timeFormat, s)
+	}
+	return time.Parse(timeFormat, s[0:len(timeFormat)])
 }
 
 // Disconnect close the connection form mongDB
@@ -0,0 +1,101 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+// prompt is the state of the text input, typed characters go to it instead of key handlers
+type prompt struct {
+	label    string
+	text     string
+	handlers []func(string)
+}
+
+// OnClick adds a handler called when the left button is clicked, x and y are the window coordinate in pixels
+func (g *GL) OnClick(handler func(x, y int)) {
+	g.clickHandlers = append(g.clickHandlers, handler)
+}
+
+// Prompt starts the text input with the label and the initial text, the handler is called with the text
+// when enter is typed. Escape cancels the input without calling the handler.
+// Handlers of the same label started by the same key share the input.
+func (g *GL) Prompt(label, text string, handler func(string)) {
+	if g.dispatching && g.prompt != nil && g.prompt.label == label {
+		g.prompt.handlers = append(g.prompt.handlers, handler)
+		return
+	}
+	g.prompt = &prompt{
+		label:    label,
+		text:     text,
+		handlers: []func(string){handler},
+	}
+}
+
+// PromptText gets the label and the text being typed, ok is false if the text input is not active
+func (g *GL) PromptText() (label, text string, ok bool) {
+	if g.prompt == nil {
+		return "", "", false
+	}
+	return g.prompt.label, g.prompt.text, true
+}
+
+func (g *GL) onChar(w *glfw.Window, char rune) {
+	if g.prompt != nil {
+		g.prompt.text += string(char)
+		return
+	}
+	g.dispatching = true
+	defer func() {
+		g.dispatching = false
+	}()
+	for _, handler := range g.keyHandlers[char] {
+		handler()
+	}
+}
+
+// onKey handles keys to edit the text input, other keys are handled by onChar
+func (g *GL) onKey(w *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
+	if g.prompt == nil || action == glfw.Release {
+		return
+	}
+	switch key {
+	case glfw.KeyEnter:
+		p := g.prompt
+		g.prompt = nil
+		for _, handler := range p.handlers {
+			handler(p.text)
+		}
+	case glfw.KeyEscape:
+		g.prompt = nil
+	case glfw.KeyBackspace:
+		if runes := []rune(g.prompt.text); len(runes) != 0 {
+			g.prompt.text = string(runes[:len(runes)-1])
+		}
+	}
+}
+
+func (g *GL) onMouseButton(w *glfw.Window, button glfw.MouseButton, action glfw.Action, mods glfw.ModifierKey) {
+	if button != glfw.MouseButtonLeft || action != glfw.Press {
+		return
+	}
+	x, y := w.GetCursorPos()
+	for _, handler := range g.clickHandlers {
+		handler(int(x), int(y))
+	}
+}
@@ -45,10 +45,24 @@ type GL struct {
 	pixelHeight  float64
 	rateX        float64
 	rateY        float64
+	panelWidth   int
+	// the scene is divided into views placed side by side, drawing goes to the selected view
+	viewCount int
+	view      int
+	viewWidth int
 
 	imageName string
 	digit     int
 	index     int
+	// saveFrame is whether the frame being drawn is saved as an image
+	saveFrame  bool
+	background Color
+
+	keyHandlers   map[rune][]func()
+	clickHandlers []func(x, y int)
+	prompt        *prompt
+	// dispatching is true while calling key handlers
+	dispatching bool
 
 	colorR float32
 	colorG float32
@@ -56,9 +70,13 @@ type GL struct {
 }
 
 // NewGL makes new utility instance of OpenGL
-func NewGL(imageName string) *GL {
+func NewGL(imageName string, background Color) *GL {
 	return &GL{
-		imageName: imageName,
+		imageName:   imageName,
+		background:  background,
+		keyHandlers: make(map[rune][]func()),
+		viewCount:   1,
+		saveFrame:   true,
 	}
 }
 
@@ -74,7 +92,7 @@ func (g *GL) Setup() {
 	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
 	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
 
-	window, err := glfw.CreateWindow(width, height, "simulator-view", nil, nil)
+	window, err := glfw.CreateWindow(width*g.viewCount+g.panelWidth, height, "simulator-view", nil, nil)
 	if err != nil {
 		log.Fatalln("failed to CreateWindow:", err)
 	}
@@ -82,6 +100,9 @@ func (g *GL) Setup() {
 	glfw.SwapInterval(1)
 
 	g.window = window
+	window.SetCharCallback(g.onChar)
+	window.SetKeyCallback(g.onKey)
+	window.SetMouseButtonCallback(g.onMouseButton)
 
 	if err := gl.Init(); err != nil {
 		log.Fatalln("failed to initialize gl:", err)
@@ -89,7 +110,7 @@ func (g *GL) Setup() {
 
 	g.setupProgram()
 
-	gl.ClearColor(1.0, 1.0, 1.0, 1.0)
+	gl.ClearColor(g.background.R, g.background.G, g.background.B, 1.0)
 }
 
 // Quit OpenGL
@@ -101,15 +122,19 @@ func (g *GL) Quit() {
 func (g *GL) Loop() bool {
 	g.window.SwapBuffers()
 
-	if g.index != 0 && len(g.imageName) != 0 {
-		g.saveImage()
+	if g.saveFrame {
+		if g.index != 0 && len(g.imageName) != 0 {
+			g.saveImage()
+		}
+		g.index++
 	}
-	g.index++
+	g.saveFrame = true
 
 	// clear and draw
 	defer func() {
 		glfw.PollEvents()
 		g.checkWindowSize()
+		g.useSceneViewport()
 		gl.Enable(gl.DEPTH_TEST)
 		gl.DepthFunc(gl.LESS)
 		gl.Clear(gl.COLOR_BUFFER_BIT | gl.DEPTH_BUFFER_BIT)
@@ -118,6 +143,38 @@ func (g *GL) Loop() bool {
 	return !g.window.ShouldClose()
 }
 
+// SetSaveFrame sets whether the frame being drawn is saved as an image, frames not updated like while pausing
+// should not be saved to keep one image for each step, it is reset to true in each loop
+func (g *GL) SetSaveFrame(save bool) {
+	g.saveFrame = save
+}
+
+// OnKey adds a handler called when the character is typed
+func (g *GL) OnKey(char rune, handler func()) {
+	g.keyHandlers[char] = append(g.keyHandlers[char], handler)
+}
+
+// SetPanelWidth sets the width of the panel at the right side of the view, it should be called before Setup
+func (g *GL) SetPanelWidth(width int) {
+	g.panelWidth = width
+}
+
+// SetViews sets the count of views dividing the scene, it should be called before Setup
+func (g *GL) SetViews(count int) {
+	g.viewCount = count
+}
+
+// SelectView selects the view to draw the scene and overlays into, overlays are placed relative to the view
+func (g *GL) SelectView(index int) {
+	g.view = index
+	g.useSceneViewport()
+}
+
+// ViewLeft gets the left of the view in pixels of the window
+func (g *GL) ViewLeft(index int) int {
+	return index * g.viewWidth
+}
+
 // SetImageDigit sets digit for saving image
 func (g *GL) SetImageDigit(digit int) {
 	g.digit = digit
@@ -131,6 +188,11 @@ func (g *GL) SetRGB(red, green, blue float32) {
 	g.colorB = blue
 }
 
+// SetColor set fill color
+func (g *GL) SetColor(c Color) {
+	g.SetRGB(c.R, c.G, c.B)
+}
+
 // Line3 draw a line at 3d coordinate space
 func (g *GL) Line3(x1, y1, z1, x2, y2, z2 float64) {
 	vertices := []float32{
@@ -236,6 +298,41 @@ func (g *GL) Box3(x, y, z, w float64) {
 	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
 }
 
+// Polygon3 fill a convex polygon at 3d coordinate space, vertices are listed as x, y, z of each vertex
+func (g *GL) Polygon3(vertices []float64) {
+	count := len(vertices) / 3
+	if count < 3 {
+		return
+	}
+
+	vertices32 := make([]float32, count*3)
+	fragments := make([]float32, count*3)
+	for i := 0; i < count; i++ {
+		vertices32[i*3] = float32(vertices[i*3])
+		vertices32[i*3+1] = float32(vertices[i*3+1])
+		vertices32[i*3+2] = float32(vertices[i*3+2])
+		fragments[i*3] = g.colorR
+		fragments[i*3+1] = g.colorG
+		fragments[i*3+2] = g.colorB
+	}
+
+	var vertexArrayObject uint32
+	gl.GenVertexArrays(1, &vertexArrayObject)
+	defer gl.DeleteVertexArrays(1, &vertexArrayObject)
+	gl.BindVertexArray(vertexArrayObject)
+
+	vertexBuffer := g.makeAndUseBuffer(0, vertices32)
+	defer gl.DeleteBuffers(1, &vertexBuffer)
+
+	fragmentBuffer := g.makeAndUseBuffer(1, fragments)
+	defer gl.DeleteBuffers(1, &fragmentBuffer)
+
+	gl.DrawArrays(gl.TRIANGLE_FAN, 0, int32(count))
+
+	gl.BindVertexArray(0)
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+}
+
 func (g *GL) setupProgram() {
 	program := gl.CreateProgram()
 	vertexShader := g.setupShader(`
@@ -318,10 +415,13 @@ func (g *GL) makeAndUseBuffer(location uint32, slice []float32) uint32 {
 }
 
 func (g *GL) checkWindowSize() {
-	width, height := g.window.GetSize()
-	if width != g.windowWidth || height != g.windowHeight {
-		g.windowWidth = width
+	windowWidth, height := g.window.GetSize()
+	if windowWidth != g.windowWidth || height != g.windowHeight {
+		g.windowWidth = windowWidth
 		g.windowHeight = height
+		// the scene is drawn at the left side of the panel
+		width := (windowWidth - g.panelWidth) / g.viewCount
+		g.viewWidth = width
 		g.pixelWidth = 1.0 / float64(width)
 		g.pixelHeight = 1.0 / float64(height)
 		if width > height {
@@ -334,6 +434,14 @@ func (g *GL) checkWindowSize() {
 	}
 }
 
+func (g *GL) useSceneViewport() {
+	gl.Viewport(int32(g.ViewLeft(g.view)), 0, int32(g.viewWidth), int32(g.windowHeight))
+}
+
+func (g *GL) useWindowViewport() {
+	gl.Viewport(0, 0, int32(g.windowWidth), int32(g.windowHeight))
+}
+
 func (g *GL) saveImage() {
 	digitStr := fmt.Sprintf("%0."+fmt.Sprintf("%d", g.digit)+"d", g.index)
 	fileName := strings.Replace(g.imageName, "@", digitStr, -1)
@@ -345,7 +453,7 @@ func (g *GL) saveImage() {
 	gl.ReadPixels(0, 0, int32(g.windowWidth), int32(g.windowHeight),
 		gl.BGR, gl.UNSIGNED_BYTE, gl.Ptr(&dataBuffer[0]))
 
-	img := image.NewRGBA(image.Rect(0, 0, g.windowHeight, g.windowHeight))
+	img := image.NewRGBA(image.Rect(0, 0, g.windowWidth, g.windowHeight))
 	idx := 0
 	for y := 0; y < g.windowHeight; y++ {
 		for x := 0; x < g.windowWidth; x++ {
@@ -0,0 +1,152 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"image"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// FontWidth is the width of a character drawn by Text in pixels
+	FontWidth = 7
+	// FontHeight is the height of a line drawn by Text in pixels
+	FontHeight = 13
+)
+
+// glyphs caches pixels of each character of the font
+var glyphs = make(map[rune][]image.Point)
+
+// Text draws a string at the window coordinate in pixels, (0, 0) is the top-left of the selected view
+// and y specifies the top of the line, it is drawn in front of everything
+func (g *GL) Text(x, y int, text string) {
+	vertices := make([]float32, 0)
+	for _, char := range text {
+		for _, p := range glyphPixels(char) {
+			vertices = g.appendPixelRect(vertices, x+p.X, y+p.Y, 1, 1)
+		}
+		x += FontWidth
+	}
+	g.drawOverlay(gl.TRIANGLES, vertices)
+}
+
+// Rect fills a rectangle at the window coordinate in pixels relative to the selected view,
+// it is drawn in front of everything
+func (g *GL) Rect(x, y, width, height int) {
+	g.drawOverlay(gl.TRIANGLES, g.appendPixelRect(nil, x, y, width, height))
+}
+
+// Polyline draws connected lines at the window coordinate in pixels relative to the selected view,
+// it is drawn in front of everything
+func (g *GL) Polyline(xs, ys []float64) {
+	left := float64(g.ViewLeft(g.view))
+	vertices := make([]float32, 0, len(xs)*3)
+	for i := range xs {
+		vertices = append(vertices,
+			float32(-1.0+2.0*(left+xs[i])/float64(g.windowWidth)),
+			float32(1.0-2.0*ys[i]/float64(g.windowHeight)),
+			-1.0)
+	}
+	g.drawOverlay(gl.LINE_STRIP, vertices)
+}
+
+// WindowSize gets the size of the window including the panel in pixels
+func (g *GL) WindowSize() (width, height int) {
+	return g.windowWidth, g.windowHeight
+}
+
+// SceneSize gets the size of the selected view of the scene excluding the panel in pixels
+func (g *GL) SceneSize() (width, height int) {
+	return g.viewWidth, g.windowHeight
+}
+
+func glyphPixels(char rune) []image.Point {
+	if pixels, ok := glyphs[char]; ok {
+		return pixels
+	}
+
+	face := basicfont.Face7x13
+	pixels := make([]image.Point, 0)
+	dr, mask, maskp, _, ok := face.Glyph(fixed.P(0, face.Ascent), char)
+	if ok {
+		for y := dr.Min.Y; y < dr.Max.Y; y++ {
+			for x := dr.Min.X; x < dr.Max.X; x++ {
+				_, _, _, a := mask.At(maskp.X+x-dr.Min.X, maskp.Y+y-dr.Min.Y).RGBA()
+				if a > 0x8000 {
+					pixels = append(pixels, image.Point{x, y})
+				}
+			}
+		}
+	}
+	glyphs[char] = pixels
+	return pixels
+}
+
+// appendPixelRect appends two triangles of the rectangle at the window coordinate relative to the selected view
+func (g *GL) appendPixelRect(vertices []float32, x, y, width, height int) []float32 {
+	x += g.ViewLeft(g.view)
+	x1 := float32(-1.0 + 2.0*float64(x)/float64(g.windowWidth))
+	y1 := float32(1.0 - 2.0*float64(y)/float64(g.windowHeight))
+	x2 := float32(-1.0 + 2.0*float64(x+width)/float64(g.windowWidth))
+	y2 := float32(1.0 - 2.0*float64(y+height)/float64(g.windowHeight))
+	return append(vertices,
+		x1, y1, -1.0,
+		x2, y1, -1.0,
+		x2, y2, -1.0,
+		x1, y1, -1.0,
+		x2, y2, -1.0,
+		x1, y2, -1.0,
+	)
+}
+
+// drawOverlay draws primitives on the whole window without depth test to put them in front of everything
+func (g *GL) drawOverlay(mode uint32, vertices []float32) {
+	if len(vertices) == 0 {
+		return
+	}
+
+	fragments := make([]float32, len(vertices))
+	for i := 0; i < len(fragments); i += 3 {
+		fragments[i] = g.colorR
+		fragments[i+1] = g.colorG
+		fragments[i+2] = g.colorB
+	}
+
+	gl.Disable(gl.DEPTH_TEST)
+	defer gl.Enable(gl.DEPTH_TEST)
+	g.useWindowViewport()
+	defer g.useSceneViewport()
+
+	var vertexArrayObject uint32
+	gl.GenVertexArrays(1, &vertexArrayObject)
+	defer gl.DeleteVertexArrays(1, &vertexArrayObject)
+	gl.BindVertexArray(vertexArrayObject)
+
+	vertexBuffer := g.makeAndUseBuffer(0, vertices)
+	defer gl.DeleteBuffers(1, &vertexBuffer)
+
+	fragmentBuffer := g.makeAndUseBuffer(1, fragments)
+	defer gl.DeleteBuffers(1, &fragmentBuffer)
+
+	gl.DrawArrays(mode, 0, int32(len(vertices)/3))
+
+	gl.BindVertexArray(0)
+	gl.BindBuffer(gl.ARRAY_BUFFER, 0)
+}
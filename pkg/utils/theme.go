@@ -0,0 +1,369 @@
+/**
+ * Copyright 2020-2020 Yuji Ito <llamerada.jp@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Color is a RGB color, each component is in the range of 0.0 to 1.0
+type Color struct {
+	R float32
+	G float32
+	B float32
+}
+
+// Theme contains colors used to draw the view
+type Theme struct {
+	Background Color   `json:"background" yaml:"background"`
+	Groups     []Color `json:"groups" yaml:"groups"`
+	Link       Color   `json:"link" yaml:"link"`
+	OneWayLink Color   `json:"oneWayLink" yaml:"oneWayLink"`
+	Ring1D     Color   `json:"ring1D" yaml:"ring1D"`
+	Missing    Color   `json:"missing" yaml:"missing"`
+	Extra      Color   `json:"extra" yaml:"extra"`
+	Text       Color   `json:"text" yaml:"text"`
+	Panel      Color   `json:"panel" yaml:"panel"`
+	Chart      Color   `json:"chart" yaml:"chart"`
+	Seed       Color   `json:"seed" yaml:"seed"`
+	Onlyone    Color   `json:"onlyone" yaml:"onlyone"`
+	Event      Color   `json:"event" yaml:"event"`
+	Isolated   Color   `json:"isolated" yaml:"isolated"`
+	Packet     Color   `json:"packet" yaml:"packet"`
+	Publish    Color   `json:"publish" yaml:"publish"`
+	Deliver    Color   `json:"deliver" yaml:"deliver"`
+	MapOwner   Color   `json:"mapOwner" yaml:"mapOwner"`
+	Focus      Color   `json:"focus" yaml:"focus"`
+	Added      Color   `json:"added" yaml:"added"`
+	Removed    Color   `json:"removed" yaml:"removed"`
+	Changed    Color   `json:"changed" yaml:"changed"`
+	// LinkStatus is indexed by link status offline, connecting, online and closing
+	LinkStatus []Color `json:"linkStatus" yaml:"linkStatus"`
+	// AuthStatus is indexed by auth status none, success and failure
+	AuthStatus []Color `json:"authStatus" yaml:"authStatus"`
+	// Colormap is a list of stops of the continuous colormap for numeric attributes
+	Colormap []Color `json:"colormap" yaml:"colormap"`
+}
+
+// viridis is used as the continuous colormap for all built-in themes
+var viridis = []Color{
+	{0.267, 0.005, 0.329},
+	{0.231, 0.322, 0.545},
+	{0.129, 0.569, 0.549},
+	{0.369, 0.788, 0.384},
+	{0.992, 0.906, 0.145},
+}
+
+var themes = map[string]Theme{
+	"light": {
+		Background: Color{1.0, 1.0, 1.0},
+		Groups: []Color{
+			{0.8, 0.0, 0.8},
+			{0.0, 0.2, 1.0},
+			{0.0, 0.8, 0.2},
+			{1.0, 0.6, 0.0},
+		},
+		Link:       Color{0.8, 0.8, 0.8},
+		OneWayLink: Color{0.8, 0.0, 0.0},
+		Ring1D:     Color{0.0, 0.6, 0.8},
+		Missing:    Color{1.0, 0.5, 0.0},
+		Extra:      Color{0.6, 0.0, 1.0},
+		Text:       Color{0.1, 0.1, 0.1},
+		Panel:      Color{0.94, 0.94, 0.94},
+		Chart:      Color{0.0, 0.45, 0.7},
+		Seed:       Color{1.0, 0.0, 0.0},
+		Onlyone:    Color{1.0, 0.0, 0.0},
+		Event:      Color{1.0, 0.0, 0.6},
+		Isolated:   Color{0.45, 0.45, 0.45},
+		Packet:     Color{0.0, 0.6, 0.6},
+		Publish:    Color{0.9, 0.5, 0.0},
+		Deliver:    Color{0.9, 0.5, 0.0},
+		MapOwner:   Color{0.5, 0.3, 0.1},
+		Focus:      Color{0.0, 0.0, 0.0},
+		Added:      Color{0.0, 0.7, 0.2},
+		Removed:    Color{0.9, 0.0, 0.0},
+		Changed:    Color{0.9, 0.6, 0.0},
+		LinkStatus: []Color{
+			{0.6, 0.6, 0.6},
+			{0.9, 0.7, 0.0},
+			{0.0, 0.7, 0.2},
+			{0.8, 0.0, 0.0},
+		},
+		AuthStatus: []Color{
+			{0.6, 0.6, 0.6},
+			{0.0, 0.7, 0.2},
+			{0.8, 0.0, 0.0},
+		},
+		Colormap: viridis,
+	},
+	"dark": {
+		Background: Color{0.06, 0.08, 0.1},
+		Groups: []Color{
+			{0.9, 0.4, 0.9},
+			{0.35, 0.6, 1.0},
+			{0.3, 0.9, 0.45},
+			{1.0, 0.7, 0.2},
+		},
+		Link:       Color{0.3, 0.3, 0.3},
+		OneWayLink: Color{1.0, 0.3, 0.3},
+		Ring1D:     Color{0.3, 0.8, 1.0},
+		Missing:    Color{1.0, 0.6, 0.2},
+		Extra:      Color{0.8, 0.5, 1.0},
+		Text:       Color{0.9, 0.9, 0.9},
+		Panel:      Color{0.12, 0.14, 0.17},
+		Chart:      Color{0.35, 0.7, 1.0},
+		Seed:       Color{1.0, 0.25, 0.25},
+		Onlyone:    Color{1.0, 0.25, 0.25},
+		Event:      Color{1.0, 0.4, 0.8},
+		Isolated:   Color{0.6, 0.6, 0.6},
+		Packet:     Color{0.3, 1.0, 1.0},
+		Publish:    Color{1.0, 0.65, 0.2},
+		Deliver:    Color{1.0, 0.65, 0.2},
+		MapOwner:   Color{0.85, 0.7, 0.5},
+		Focus:      Color{1.0, 1.0, 1.0},
+		Added:      Color{0.3, 0.9, 0.45},
+		Removed:    Color{1.0, 0.3, 0.3},
+		Changed:    Color{1.0, 0.8, 0.2},
+		LinkStatus: []Color{
+			{0.45, 0.45, 0.45},
+			{1.0, 0.8, 0.2},
+			{0.3, 0.9, 0.45},
+			{1.0, 0.3, 0.3},
+		},
+		AuthStatus: []Color{
+			{0.45, 0.45, 0.45},
+			{0.3, 0.9, 0.45},
+			{1.0, 0.3, 0.3},
+		},
+		Colormap: viridis,
+	},
+	// colorblind uses the palette by Okabe and Ito
+	"colorblind": {
+		Background: Color{1.0, 1.0, 1.0},
+		Groups: []Color{
+			{0.8, 0.475, 0.655},
+			{0.0, 0.447, 0.698},
+			{0.0, 0.62, 0.451},
+			{0.902, 0.624, 0.0},
+			{0.337, 0.706, 0.914},
+			{0.941, 0.894, 0.259},
+		},
+		Link:       Color{0.75, 0.75, 0.75},
+		OneWayLink: Color{0.835, 0.369, 0.0},
+		Ring1D:     Color{0.2, 0.2, 0.2},
+		Missing:    Color{0.0, 0.0, 0.0},
+		Extra:      Color{0.4, 0.4, 0.4},
+		Text:       Color{0.0, 0.0, 0.0},
+		Panel:      Color{0.94, 0.94, 0.94},
+		Chart:      Color{0.0, 0.447, 0.698},
+		Seed:       Color{0.835, 0.369, 0.0},
+		Onlyone:    Color{0.835, 0.369, 0.0},
+		Event:      Color{0.337, 0.706, 0.914},
+		Isolated:   Color{0.5, 0.5, 0.5},
+		Packet:     Color{0.0, 0.62, 0.451},
+		Publish:    Color{0.902, 0.624, 0.0},
+		Deliver:    Color{0.902, 0.624, 0.0},
+		MapOwner:   Color{0.8, 0.475, 0.655},
+		Focus:      Color{0.0, 0.0, 0.0},
+		Added:      Color{0.0, 0.62, 0.451},
+		Removed:    Color{0.835, 0.369, 0.0},
+		Changed:    Color{0.337, 0.706, 0.914},
+		LinkStatus: []Color{
+			{0.6, 0.6, 0.6},
+			{0.902, 0.624, 0.0},
+			{0.0, 0.447, 0.698},
+			{0.835, 0.369, 0.0},
+		},
+		AuthStatus: []Color{
+			{0.6, 0.6, 0.6},
+			{0.0, 0.447, 0.698},
+			{0.835, 0.369, 0.0},
+		},
+		Colormap: viridis,
+	},
+}
+
+// ParseColor parses a color written like `#rrggbb`
+func ParseColor(s string) (Color, error) {
+	var r, g, b uint8
+	if len(s) != 7 || s[0] != '#' {
+		return Color{}, fmt.Errorf("color should be written like #rrggbb: %s", s)
+	}
+	if _, err := fmt.Sscanf(s[1:], "%02x%02x%02x", &r, &g, &b); err != nil {
+		return Color{}, fmt.Errorf("color should be written like #rrggbb: %s", s)
+	}
+	return Color{float32(r) / 255.0, float32(g) / 255.0, float32(b) / 255.0}, nil
+}
+
+// UnmarshalText decodes a color from JSON string
+func (c *Color) UnmarshalText(text []byte) error {
+	color, err := ParseColor(string(text))
+	if err != nil {
+		return err
+	}
+	*c = color
+	return nil
+}
+
+// UnmarshalYAML decodes a color from YAML string
+func (c *Color) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return c.UnmarshalText([]byte(s))
+}
+
+// Mix returns the color blended with another color, rate 0.0 means c and rate 1.0 means another
+func (c Color) Mix(another Color, rate float32) Color {
+	return Color{
+		R: c.R + (another.R-c.R)*rate,
+		G: c.G + (another.G-c.G)*rate,
+		B: c.B + (another.B-c.B)*rate,
+	}
+}
+
+// LoadTheme gets built-in theme by the name, or reads the theme from YAML/JSON file
+func LoadTheme(name string) (*Theme, error) {
+	if theme, ok := themes[name]; ok {
+		return theme.clone(), nil
+	}
+
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("theme should be one of light, dark, colorblind or a theme file: %w", err)
+	}
+
+	var unmarshal func([]byte, interface{}) error
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		unmarshal = json.Unmarshal
+	case ".yaml", ".yml":
+		unmarshal = yaml.Unmarshal
+	default:
+		return nil, fmt.Errorf("theme file should be .json, .yaml or .yml: %s", name)
+	}
+
+	// overwrite the base theme by the values in the file
+	var base struct {
+		Base string `json:"base" yaml:"base"`
+	}
+	if err = unmarshal(data, &base); err != nil {
+		return nil, err
+	}
+	if len(base.Base) == 0 {
+		base.Base = "light"
+	}
+	builtin, ok := themes[base.Base]
+	if !ok {
+		return nil, fmt.Errorf("unknown base theme: %s", base.Base)
+	}
+	theme := builtin.clone()
+	if err = unmarshal(data, theme); err != nil {
+		return nil, err
+	}
+	if len(theme.Groups) == 0 {
+		return nil, fmt.Errorf("theme should have one or more group colors: %s", name)
+	}
+	if len(theme.LinkStatus) != 4 || len(theme.AuthStatus) != 3 {
+		return nil, fmt.Errorf("theme should have 4 link status colors and 3 auth status colors: %s", name)
+	}
+	if len(theme.Colormap) < 2 {
+		return nil, fmt.Errorf("theme should have two or more colormap stops: %s", name)
+	}
+
+	return theme, nil
+}
+
+// clone copies the theme with slices not to overwrite built-in themes by decoding the file into them
+func (t *Theme) clone() *Theme {
+	theme := *t
+	theme.Groups = append([]Color{}, t.Groups...)
+	theme.LinkStatus = append([]Color{}, t.LinkStatus...)
+	theme.AuthStatus = append([]Color{}, t.AuthStatus...)
+	theme.Colormap = append([]Color{}, t.Colormap...)
+	return &theme
+}
+
+// GroupColor gets the color for the group, colors are generated for groups exceed the palette
+func (t *Theme) GroupColor(group int) Color {
+	if group < len(t.Groups) {
+		return t.Groups[group]
+	}
+
+	// spread hue by golden ratio to keep neighboring groups distinguishable
+	hue := math.Mod(float64(group-len(t.Groups))*0.618033988749895+0.1, 1.0)
+	if t.isDark() {
+		return hsvToColor(hue, 0.55, 0.95)
+	}
+	return hsvToColor(hue, 0.8, 0.8)
+}
+
+// MapColor gets the color for the value in the range of 0.0 to 1.0 from the continuous colormap
+func (t *Theme) MapColor(value float64) Color {
+	if math.IsNaN(value) || value < 0.0 {
+		value = 0.0
+	} else if value > 1.0 {
+		value = 1.0
+	}
+	pos := value * float64(len(t.Colormap)-1)
+	idx := int(pos)
+	if idx >= len(t.Colormap)-1 {
+		return t.Colormap[len(t.Colormap)-1]
+	}
+	return t.Colormap[idx].Mix(t.Colormap[idx+1], float32(pos-float64(idx)))
+}
+
+// HashColor gets the color distinguished by the hash value
+func (t *Theme) HashColor(hash uint32) Color {
+	return t.GroupColor(len(t.Groups) + int(hash%4096))
+}
+
+func (t *Theme) isDark() bool {
+	bg := t.Background
+	return 0.299*bg.R+0.587*bg.G+0.114*bg.B < 0.5
+}
+
+func hsvToColor(h, s, v float64) Color {
+	i := math.Floor(h * 6.0)
+	f := h*6.0 - i
+	p := v * (1.0 - s)
+	q := v * (1.0 - f*s)
+	t := v * (1.0 - (1.0-f)*s)
+
+	switch int(i) % 6 {
+	case 0:
+		return Color{float32(v), float32(t), float32(p)}
+	case 1:
+		return Color{float32(q), float32(v), float32(p)}
+	case 2:
+		return Color{float32(p), float32(v), float32(t)}
+	case 3:
+		return Color{float32(p), float32(q), float32(v)}
+	case 4:
+		return Color{float32(t), float32(p), float32(v)}
+	default:
+		return Color{float32(v), float32(p), float32(q)}
+	}
+}